@@ -4,10 +4,13 @@ import (
 	"github.com/go-redis/redis"
 	"gorm.io/gorm"
 	"app/config"
+	"app/adaptor/repo/battery"
 	"app/adaptor/repo/device"
 	"app/adaptor/repo/friend"
 	"app/adaptor/repo/geofence"
 	"app/adaptor/repo/location"
+	"app/adaptor/repo/settings"
+	"app/adaptor/repo/visit"
 	redisCache "app/adaptor/redis"
 )
 
@@ -18,12 +21,20 @@ type IAdaptor interface {
 
 	// 位置缓存
 	NewLocationCache() *redisCache.LocationCache
+	NewGeofenceCache() *redisCache.GeofenceCache
+	NewPresenceCache() *redisCache.PresenceCache
+	NewPubSub() *redisCache.PubSub
+	NewBatteryCache() *redisCache.BatteryCache
+	NewVisitCache() *redisCache.VisitCache
 
 	// 仓储
 	NewLocationRepository() *location.LocationRepository
 	NewFriendRepository() *friend.FriendRepository
 	NewDeviceRepository() *device.DeviceRepository
 	NewGeofenceRepository() *geofence.GeofenceRepository
+	NewSettingsRepository() *settings.SettingsRepository
+	NewVisitRepository() *visit.VisitRepository
+	NewBatteryRepository() *battery.BatteryRepository
 }
 
 type Adaptor struct {
@@ -57,6 +68,26 @@ func (a *Adaptor) NewLocationCache() *redisCache.LocationCache {
 	return redisCache.NewLocationCache(a.redis)
 }
 
+func (a *Adaptor) NewGeofenceCache() *redisCache.GeofenceCache {
+	return redisCache.NewGeofenceCache(a.redis)
+}
+
+func (a *Adaptor) NewPresenceCache() *redisCache.PresenceCache {
+	return redisCache.NewPresenceCache(a.redis)
+}
+
+func (a *Adaptor) NewPubSub() *redisCache.PubSub {
+	return redisCache.NewPubSub(a.redis)
+}
+
+func (a *Adaptor) NewBatteryCache() *redisCache.BatteryCache {
+	return redisCache.NewBatteryCache(a.redis)
+}
+
+func (a *Adaptor) NewVisitCache() *redisCache.VisitCache {
+	return redisCache.NewVisitCache(a.redis)
+}
+
 // 仓储
 func (a *Adaptor) NewLocationRepository() *location.LocationRepository {
 	return location.NewLocationRepository(a.db)
@@ -73,3 +104,15 @@ func (a *Adaptor) NewDeviceRepository() *device.DeviceRepository {
 func (a *Adaptor) NewGeofenceRepository() *geofence.GeofenceRepository {
 	return geofence.NewGeofenceRepository(a.db)
 }
+
+func (a *Adaptor) NewSettingsRepository() *settings.SettingsRepository {
+	return settings.NewSettingsRepository(a.db)
+}
+
+func (a *Adaptor) NewVisitRepository() *visit.VisitRepository {
+	return visit.NewVisitRepository(a.db)
+}
+
+func (a *Adaptor) NewBatteryRepository() *battery.BatteryRepository {
+	return battery.NewBatteryRepository(a.db)
+}
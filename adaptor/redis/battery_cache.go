@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// 实体的放电周期状态，"<entity_type>:<entity_id>"
+	batteryStateKey = "battery:state:%s:%s"
+
+	batteryStateTTL = 30 * 24 * time.Hour
+)
+
+// BatteryState 实体当前放电周期的电量状态
+type BatteryState struct {
+	Level int       `json:"level"` // 最近一次电量
+	At    time.Time `json:"at"`
+
+	// 本放电周期内的最低电量，电量比它高出一定幅度时视为已充电
+	MinLevel int `json:"min_level"`
+
+	// 耗电速度的参考点，充电或窗口用尽后重置
+	RefLevel int       `json:"ref_level"`
+	RefAt    time.Time `json:"ref_at"`
+
+	// 本放电周期内已产生的告警
+	LowAlerted   bool `json:"low_alerted"`
+	DrainAlerted bool `json:"drain_alerted"`
+}
+
+// BatteryCache 电量状态缓存
+type BatteryCache struct {
+	client *redis.Client
+}
+
+// NewBatteryCache 创建电量状态缓存
+func NewBatteryCache(client *redis.Client) *BatteryCache {
+	return &BatteryCache{client: client}
+}
+
+// GetState 获取实体的放电周期状态，未记录过时返回 nil
+func (c *BatteryCache) GetState(entityType, entityID string) (*BatteryState, error) {
+	data, err := c.client.Get(fmt.Sprintf(batteryStateKey, entityType, entityID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st BatteryState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// SetState 保存实体的放电周期状态
+func (c *BatteryCache) SetState(entityType, entityID string, st *BatteryState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(fmt.Sprintf(batteryStateKey, entityType, entityID), data, batteryStateTTL).Err()
+}
+
+// DeleteState 清除实体的放电周期状态
+func (c *BatteryCache) DeleteState(entityType, entityID string) error {
+	return c.client.Del(fmt.Sprintf(batteryStateKey, entityType, entityID)).Err()
+}
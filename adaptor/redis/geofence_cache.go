@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// 围栏内外状态，hash field 为 "<entity_type>:<entity_id>"
+	geofenceStateKey = "geofence:state:%d"
+
+	geofenceStateInside  = "in"
+	geofenceStateOutside = "out"
+)
+
+// GeofenceCache 地理围栏状态缓存
+type GeofenceCache struct {
+	client *redis.Client
+}
+
+// NewGeofenceCache 创建地理围栏状态缓存
+func NewGeofenceCache(client *redis.Client) *GeofenceCache {
+	return &GeofenceCache{client: client}
+}
+
+// GetStates 批量获取实体在各围栏内的上次状态，未记录过的围栏不会出现在结果中
+func (c *GeofenceCache) GetStates(geofenceIDs []int64, entityType, entityID string) (map[int64]bool, error) {
+	states := make(map[int64]bool, len(geofenceIDs))
+	if len(geofenceIDs) == 0 {
+		return states, nil
+	}
+
+	field := geofenceStateField(entityType, entityID)
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(geofenceIDs))
+	for i, id := range geofenceIDs {
+		cmds[i] = pipe.HGet(fmt.Sprintf(geofenceStateKey, id), field)
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states[geofenceIDs[i]] = val == geofenceStateInside
+	}
+	return states, nil
+}
+
+// SetState 记录实体在围栏内的状态
+func (c *GeofenceCache) SetState(geofenceID int64, entityType, entityID string, inside bool) error {
+	val := geofenceStateOutside
+	if inside {
+		val = geofenceStateInside
+	}
+	key := fmt.Sprintf(geofenceStateKey, geofenceID)
+	return c.client.HSet(key, geofenceStateField(entityType, entityID), val).Err()
+}
+
+// DeleteStates 清除围栏的所有状态记录
+func (c *GeofenceCache) DeleteStates(geofenceID int64) error {
+	return c.client.Del(fmt.Sprintf(geofenceStateKey, geofenceID)).Err()
+}
+
+func geofenceStateField(entityType, entityID string) string {
+	return entityType + ":" + entityID
+}
@@ -13,6 +13,7 @@ import (
 const (
 	// Location cache keys
 	userLocationKey    = "loc:user:%d"
+	userRecordedAtKey  = "loc:user:recorded:%d" // 缓存位置的定位时间（毫秒）
 	deviceLocationKey  = "loc:device:%s"
 	friendsKey         = "friends:%d"
 	userSettingsKey    = "settings:%d"
@@ -27,6 +28,18 @@ const (
 	settingsTTL = 1 * time.Hour
 )
 
+// setUserLocationScript 定位时间不早于已缓存位置时才写入，返回 1 表示已写入
+var setUserLocationScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[2])
+if cur and tonumber(cur) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+redis.call('SET', KEYS[2], ARGV[2], 'EX', ARGV[3])
+redis.call('GEOADD', KEYS[3], ARGV[4], ARGV[5], ARGV[6])
+return 1
+`)
+
 // LocationCache 位置缓存服务
 type LocationCache struct {
 	client *redis.Client
@@ -38,22 +51,20 @@ func NewLocationCache(client *redis.Client) *LocationCache {
 }
 
 // SetUserLocation 设置用户位置
+// 已缓存位置的定位时间更晚时不覆盖，避免乱序到达的旧位置替换最新位置
 func (c *LocationCache) SetUserLocation(userID int64, loc *dto.LocationResp) error {
 	data, err := json.Marshal(loc)
 	if err != nil {
 		return err
 	}
-	key := fmt.Sprintf(userLocationKey, userID)
-
-	pipe := c.client.Pipeline()
-	pipe.Set(key, data, locationTTL)
-	pipe.GeoAdd(geoUsersKey, &redis.GeoLocation{
-		Longitude: loc.Longitude,
-		Latitude:  loc.Latitude,
-		Name:      fmt.Sprintf("%d", userID),
-	})
-	_, err = pipe.Exec()
-	return err
+	keys := []string{
+		fmt.Sprintf(userLocationKey, userID),
+		fmt.Sprintf(userRecordedAtKey, userID),
+		geoUsersKey,
+	}
+	recordedAt := loc.RecordedAt.UnixNano() / int64(time.Millisecond)
+	return setUserLocationScript.Run(c.client, keys,
+		data, recordedAt, int64(locationTTL/time.Second), loc.Longitude, loc.Latitude, userID).Err()
 }
 
 // GetUserLocation 获取用户位置
@@ -73,6 +84,34 @@ func (c *LocationCache) GetUserLocation(userID int64) (*dto.LocationResp, error)
 	return &loc, nil
 }
 
+// GetUserLocations 批量获取用户位置，未命中的用户不在结果中
+func (c *LocationCache) GetUserLocations(userIDs []int64) (map[int64]*dto.LocationResp, error) {
+	result := make(map[int64]*dto.LocationResp, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = fmt.Sprintf(userLocationKey, id)
+	}
+	values, err := c.client.MGet(keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var loc dto.LocationResp
+		if err := json.Unmarshal([]byte(data), &loc); err != nil {
+			return nil, err
+		}
+		result[userIDs[i]] = &loc
+	}
+	return result, nil
+}
+
 // SetDeviceLocation 设置设备位置
 func (c *LocationCache) SetDeviceLocation(deviceID string, loc *dto.LocationResp) error {
 	data, err := json.Marshal(loc)
@@ -114,7 +153,7 @@ func (c *LocationCache) DeleteUserLocation(userID int64) error {
 	key := fmt.Sprintf(userLocationKey, userID)
 	// 使用 ZREM 删除 GEO set 中的成员
 	c.client.ZRem(geoUsersKey, fmt.Sprintf("%d", userID))
-	return c.client.Del(key).Err()
+	return c.client.Del(key, fmt.Sprintf(userRecordedAtKey, userID)).Err()
 }
 
 // DeleteDeviceLocation 删除设备位置缓存
@@ -145,6 +184,21 @@ func (c *LocationCache) GeoRadius(key string, lon, lat, radiusMeters float64) ([
 	return names, nil
 }
 
+// GeoRadiusWithDist GEO半径查询，返回成员及其距离（米），按距离升序
+func (c *LocationCache) GeoRadiusWithDist(key string, lon, lat, radiusMeters float64) ([]redis.GeoLocation, error) {
+	return c.client.GeoRadius(key, lon, lat, &redis.GeoRadiusQuery{
+		Radius:   radiusMeters,
+		Unit:     "m",
+		WithDist: true,
+		Sort:     "ASC",
+	}).Result()
+}
+
+// GeoRadiusUsersWithDist 查询附近用户及距离
+func (c *LocationCache) GeoRadiusUsersWithDist(lon, lat, radiusMeters float64) ([]redis.GeoLocation, error) {
+	return c.GeoRadiusWithDist(geoUsersKey, lon, lat, radiusMeters)
+}
+
 // GeoRadiusUsers 查询附近用户
 func (c *LocationCache) GeoRadiusUsers(lon, lat, radiusMeters float64) ([]string, error) {
 	return c.GeoRadius(geoUsersKey, lon, lat, radiusMeters)
@@ -0,0 +1,166 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"app/service/dto"
+)
+
+const (
+	// 用户的在线来源（连接或位置上报），member 为来源标识，score 为过期时间戳
+	presenceSourcesKey = "presence:sources:%d"
+	// 在线用户索引，member 为用户ID，score 为最晚过期时间戳，用于扫描超时离线
+	presenceOnlineKey = "presence:online"
+	// 用户最后活跃时间，field 为用户ID
+	presenceLastSeenKey = "presence:last_seen"
+
+	presenceSourcesTTL = 1 * time.Hour
+)
+
+// touchScript 记录在线来源，返回 1 表示用户由离线变为在线
+var touchScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+local online = redis.call('ZCARD', KEYS[1])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+local cur = redis.call('ZSCORE', KEYS[2], ARGV[4])
+if not cur or tonumber(cur) < tonumber(ARGV[3]) then
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+end
+redis.call('HSET', KEYS[3], ARGV[4], ARGV[2])
+if online == 0 then
+	return 1
+end
+return 0
+`)
+
+// removeScript 移除在线来源，返回 1 表示用户由在线变为离线
+var removeScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[3], ARGV[3], ARGV[2])
+if removed == 0 then
+	return 0
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+if redis.call('ZCARD', KEYS[1]) > 0 then
+	return 0
+end
+return redis.call('ZREM', KEYS[2], ARGV[3])
+`)
+
+// expireScript 清理过期来源，仍有有效来源时顺延索引，返回 1 表示用户已超时离线
+var expireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if #last > 0 then
+	redis.call('ZADD', KEYS[2], last[2], ARGV[2])
+	return 0
+end
+return redis.call('ZREM', KEYS[2], ARGV[2])
+`)
+
+// PresenceCache 在线状态缓存
+type PresenceCache struct {
+	client *redis.Client
+}
+
+// NewPresenceCache 创建在线状态缓存
+func NewPresenceCache(client *redis.Client) *PresenceCache {
+	return &PresenceCache{client: client}
+}
+
+// Touch 记录一次在线心跳，source 标识在线来源，ttl 内未再次心跳则该来源失效
+// 返回用户是否由离线变为在线
+func (c *PresenceCache) Touch(userID int64, source string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	keys := presenceKeys(userID)
+	res, err := touchScript.Run(c.client, keys,
+		source, now.Unix(), now.Add(ttl).Unix(), userID, int64(presenceSourcesTTL/time.Second)).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Remove 移除在线来源，返回用户是否由在线变为离线
+func (c *PresenceCache) Remove(userID int64, source string) (bool, error) {
+	res, err := removeScript.Run(c.client, presenceKeys(userID), source, time.Now().Unix(), userID).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// ExpiredUsers 获取在线索引中可能已超时的用户
+func (c *PresenceCache) ExpiredUsers(limit int64) ([]int64, error) {
+	members, err := c.client.ZRangeByScore(presenceOnlineKey, redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int64, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	return userIDs, nil
+}
+
+// Expire 检查用户是否已无有效在线来源，是则移出在线索引
+// 多个实例同时检查时只有一个返回 true
+func (c *PresenceCache) Expire(userID int64) (bool, error) {
+	keys := presenceKeys(userID)[:2]
+	res, err := expireScript.Run(c.client, keys, time.Now().Unix(), userID).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// GetPresences 批量获取用户在线状态
+func (c *PresenceCache) GetPresences(userIDs []int64) (map[int64]*dto.PresenceResp, error) {
+	result := make(map[int64]*dto.PresenceResp, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	min := "(" + strconv.FormatInt(time.Now().Unix(), 10)
+	fields := make([]string, len(userIDs))
+	pipe := c.client.Pipeline()
+	counts := make([]*redis.IntCmd, len(userIDs))
+	for i, id := range userIDs {
+		fields[i] = strconv.FormatInt(id, 10)
+		counts[i] = pipe.ZCount(fmt.Sprintf(presenceSourcesKey, id), min, "+inf")
+	}
+	lastSeen := pipe.HMGet(presenceLastSeenKey, fields...)
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	seen := lastSeen.Val()
+	for i, id := range userIDs {
+		p := &dto.PresenceResp{Online: counts[i].Val() > 0}
+		if i < len(seen) {
+			if s, ok := seen[i].(string); ok {
+				if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+					t := time.Unix(ts, 0)
+					p.LastSeen = &t
+				}
+			}
+		}
+		result[id] = p
+	}
+	return result, nil
+}
+
+func presenceKeys(userID int64) []string {
+	return []string{fmt.Sprintf(presenceSourcesKey, userID), presenceOnlineKey, presenceLastSeenKey}
+}
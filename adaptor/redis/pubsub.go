@@ -3,6 +3,8 @@ package redis
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/go-redis/redis"
 
@@ -15,6 +17,15 @@ const (
 	wsMessageChannel      = "channel:ws:message"
 )
 
+// LocationUpdate 跨实例广播的位置更新
+type LocationUpdate struct {
+	Type       string            `json:"type"`
+	EntityType string            `json:"entity_type"`
+	EntityID   string            `json:"entity_id"`
+	Location   *dto.LocationResp `json:"location"`
+	Viewers    []int64           `json:"viewers"` // 有权查看该位置的用户
+}
+
 // PubSub 发布订阅服务
 type PubSub struct {
 	client *redis.Client
@@ -26,12 +37,13 @@ func NewPubSub(client *redis.Client) *PubSub {
 }
 
 // PublishLocationUpdate 发布位置更新
-func (p *PubSub) PublishLocationUpdate(entityType string, entityID string, loc *dto.LocationResp) error {
-	msg := map[string]interface{}{
-		"type":        "location_update",
-		"entity_type": entityType,
-		"entity_id":   entityID,
-		"location":    loc,
+func (p *PubSub) PublishLocationUpdate(entityType string, entityID string, loc *dto.LocationResp, viewers []int64) error {
+	msg := &LocationUpdate{
+		Type:       "location_update",
+		EntityType: entityType,
+		EntityID:   entityID,
+		Location:   loc,
+		Viewers:    viewers,
 	}
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -50,6 +62,34 @@ func (p *PubSub) PublishWSMessage(userID int64, msg interface{}) error {
 	return p.client.Publish(key, data).Err()
 }
 
+// SubscribeRealtime 订阅位置更新频道和所有用户的 WebSocket 消息频道
+func (p *PubSub) SubscribeRealtime() (*redis.PubSub, error) {
+	ps := p.client.Subscribe(locationUpdateChannel)
+	if err := ps.PSubscribe(wsMessageChannel + ":*"); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// IsLocationUpdateChannel 是否为位置更新频道
+func IsLocationUpdateChannel(channel string) bool {
+	return channel == locationUpdateChannel
+}
+
+// ParseWSMessageChannel 从用户 WebSocket 消息频道名中解析用户ID
+func ParseWSMessageChannel(channel string) (int64, bool) {
+	prefix := wsMessageChannel + ":"
+	if !strings.HasPrefix(channel, prefix) {
+		return 0, false
+	}
+	userID, err := strconv.ParseInt(channel[len(prefix):], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
+
 // Subscribe 订阅频道
 func (p *PubSub) Subscribe(channels ...string) *redis.PubSub {
 	return p.client.Subscribe(channels...)
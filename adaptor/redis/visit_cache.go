@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// 待检测停留的用户，member 为用户ID，score 为新保存位置点中最早的定位时间戳
+const visitPendingKey = "visit:pending"
+
+// markPendingScript 标记用户待检测，已标记时保留更早的时间
+var markPendingScript = redis.NewScript(`
+local cur = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if not cur or tonumber(cur) > tonumber(ARGV[1]) then
+	redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+end
+return 0
+`)
+
+// popPendingScript 取出最多 ARGV[1] 个待检测用户，多个实例同时取出时不会重复
+var popPendingScript = redis.NewScript(`
+local items = redis.call('ZRANGE', KEYS[1], 0, tonumber(ARGV[1]) - 1, 'WITHSCORES')
+for i = 1, #items, 2 do
+	redis.call('ZREM', KEYS[1], items[i])
+end
+return items
+`)
+
+// PendingVisit 待检测停留的用户
+type PendingVisit struct {
+	UserID int64
+	Since  time.Time // 新保存位置点中最早的定位时间
+}
+
+// VisitCache 停留检测队列
+type VisitCache struct {
+	client *redis.Client
+}
+
+// NewVisitCache 创建停留检测队列
+func NewVisitCache(client *redis.Client) *VisitCache {
+	return &VisitCache{client: client}
+}
+
+// MarkPending 标记用户有新保存的位置点，since 为其中最早的定位时间
+func (c *VisitCache) MarkPending(userID int64, since time.Time) error {
+	return markPendingScript.Run(c.client, []string{visitPendingKey}, since.Unix(), userID).Err()
+}
+
+// PopPending 取出待检测的用户，最早标记的时间靠前
+func (c *VisitCache) PopPending(limit int64) ([]PendingVisit, error) {
+	res, err := popPendingScript.Run(c.client, []string{visitPendingKey}, limit).Result()
+	if err != nil {
+		return nil, err
+	}
+	items, _ := res.([]interface{})
+	pending := make([]PendingVisit, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		member, _ := items[i].(string)
+		score, _ := items[i+1].(string)
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		since, err := strconv.ParseInt(score, 10, 64)
+		if err != nil {
+			continue
+		}
+		pending = append(pending, PendingVisit{UserID: userID, Since: time.Unix(since, 0)})
+	}
+	return pending, nil
+}
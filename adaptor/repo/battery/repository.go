@@ -0,0 +1,44 @@
+package battery
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"app/adaptor/repo/model"
+)
+
+// IBatteryRepository 电量告警仓储接口
+type IBatteryRepository interface {
+	CreateAlert(ctx context.Context, alert *model.BatteryAlert) error
+	GetAlerts(ctx context.Context, userID int64, entityType, entityID string, limit, offset int) ([]*model.BatteryAlert, error)
+}
+
+// BatteryRepository 电量告警仓储实现
+type BatteryRepository struct {
+	db *gorm.DB
+}
+
+// NewBatteryRepository 创建电量告警仓储
+func NewBatteryRepository(db *gorm.DB) *BatteryRepository {
+	return &BatteryRepository{db: db}
+}
+
+// CreateAlert 保存电量告警
+func (r *BatteryRepository) CreateAlert(ctx context.Context, alert *model.BatteryAlert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+// GetAlerts 获取用户收到的电量告警，按时间倒序；entityType、entityID 为空时不过滤
+func (r *BatteryRepository) GetAlerts(ctx context.Context, userID int64, entityType, entityID string, limit, offset int) ([]*model.BatteryAlert, error) {
+	var alerts []*model.BatteryAlert
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&alerts).Error
+	return alerts, err
+}
@@ -2,8 +2,10 @@ package device
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"app/adaptor/repo/model"
 )
@@ -14,10 +16,17 @@ type IDeviceRepository interface {
 	Get(ctx context.Context, deviceID string) (*model.Device, error)
 	GetByUser(ctx context.Context, userID int64) ([]*model.Device, error)
 	Update(ctx context.Context, device *model.Device) error
-	UpdateStatus(ctx context.Context, deviceID string, batteryLevel int, connectionStatus model.DeviceConnectionStatus) error
+	UpdateSettings(ctx context.Context, deviceID string, name *string, notificationEnabled *bool, lowBatteryThreshold *int) error
+	Touch(ctx context.Context, deviceID string, batteryLevel *int, seenAt time.Time) error
+	UpdateBattery(ctx context.Context, deviceID string, batteryLevel int) error
+	TransitStatus(ctx context.Context, deviceID string, to model.DeviceConnectionStatus, reason string, at time.Time) (*model.DeviceStatusEvent, error)
+	MarkOffline(ctx context.Context, deviceID string, staleBefore, at time.Time) (*model.DeviceStatusEvent, error)
+	GetStale(ctx context.Context, staleBefore time.Time, limit int) ([]*model.Device, error)
+	GetStatusEvents(ctx context.Context, deviceID string, limit int) ([]*model.DeviceStatusEvent, error)
 	Delete(ctx context.Context, deviceID string) error
 	IsBound(ctx context.Context, deviceID string) (bool, error)
 	GetUserByDevice(ctx context.Context, deviceID string) (int64, error)
+	UpdateAPIKey(ctx context.Context, deviceID string, keyHash string) error
 }
 
 // DeviceRepository 设备仓储实现
@@ -57,14 +66,113 @@ func (r *DeviceRepository) Update(ctx context.Context, device *model.Device) err
 	return r.db.WithContext(ctx).Save(device).Error
 }
 
-// UpdateStatus 更新设备状态
-func (r *DeviceRepository) UpdateStatus(ctx context.Context, deviceID string, batteryLevel int, connectionStatus model.DeviceConnectionStatus) error {
-	return r.db.WithContext(ctx).Model(&model.Device{}).
-		Where("id = ?", deviceID).
-		Updates(map[string]interface{}{
-			"battery_level":      batteryLevel,
-			"connection_status":  connectionStatus,
+// UpdateSettings 更新设备设置，只写入非空参数对应的列
+// 不整行保存，避免覆盖心跳与离线扫描并发写入的在线状态
+func (r *DeviceRepository) UpdateSettings(ctx context.Context, deviceID string, name *string, notificationEnabled *bool, lowBatteryThreshold *int) error {
+	updates := map[string]interface{}{}
+	if name != nil {
+		updates["name"] = *name
+	}
+	if notificationEnabled != nil {
+		updates["notification_enabled"] = *notificationEnabled
+	}
+	if lowBatteryThreshold != nil {
+		updates["low_battery_threshold"] = *lowBatteryThreshold
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&model.Device{}).Where("id = ?", deviceID).Updates(updates).Error
+}
+
+// Touch 记录设备最近一次上报或心跳时间，batteryLevel 非空时同时更新电量
+func (r *DeviceRepository) Touch(ctx context.Context, deviceID string, batteryLevel *int, seenAt time.Time) error {
+	updates := map[string]interface{}{"last_seen_at": seenAt}
+	if batteryLevel != nil {
+		updates["battery_level"] = *batteryLevel
+	}
+	return r.db.WithContext(ctx).Model(&model.Device{}).Where("id = ?", deviceID).Updates(updates).Error
+}
+
+// UpdateBattery 更新设备电量
+func (r *DeviceRepository) UpdateBattery(ctx context.Context, deviceID string, batteryLevel int) error {
+	return r.db.WithContext(ctx).Model(&model.Device{}).Where("id = ?", deviceID).Update("battery_level", batteryLevel).Error
+}
+
+// TransitStatus 将设备置为指定连接状态，状态发生变化时在同一事务中写入变化记录并返回
+// 设备不存在或状态未变化时返回 nil
+func (r *DeviceRepository) TransitStatus(ctx context.Context, deviceID string, to model.DeviceConnectionStatus, reason string, at time.Time) (*model.DeviceStatusEvent, error) {
+	return r.transit(ctx, deviceID, to, reason, at, func(*model.Device) bool { return true })
+}
+
+// MarkOffline 将 staleBefore 之后没有上报或心跳的在线设备置为离线
+// 加锁后重新检查，多个实例同时扫描时只有一个会写入变化记录
+func (r *DeviceRepository) MarkOffline(ctx context.Context, deviceID string, staleBefore, at time.Time) (*model.DeviceStatusEvent, error) {
+	return r.transit(ctx, deviceID, model.DeviceConnectionOffline, model.DeviceStatusReasonTimeout, at, func(d *model.Device) bool {
+		return d.LastSeenAt == nil || d.LastSeenAt.Before(staleBefore)
+	})
+}
+
+func (r *DeviceRepository) transit(ctx context.Context, deviceID string, to model.DeviceConnectionStatus, reason string, at time.Time, allow func(*model.Device) bool) (*model.DeviceStatusEvent, error) {
+	var event *model.DeviceStatusEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var d model.Device
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&d, "id = ?", deviceID).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.ConnectionStatus == to || !allow(&d) {
+			return nil
+		}
+
+		err = tx.Model(&model.Device{}).Where("id = ?", deviceID).Updates(map[string]interface{}{
+			"connection_status": to,
+			"status_changed_at": at,
 		}).Error
+		if err != nil {
+			return err
+		}
+
+		e := &model.DeviceStatusEvent{
+			DeviceID:   deviceID,
+			UserID:     d.UserID,
+			FromStatus: d.ConnectionStatus,
+			ToStatus:   to,
+			Reason:     reason,
+			LastSeenAt: d.LastSeenAt,
+			CreatedAt:  at,
+		}
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		event = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetStale 获取 staleBefore 之后没有上报或心跳的在线设备
+func (r *DeviceRepository) GetStale(ctx context.Context, staleBefore time.Time, limit int) ([]*model.Device, error) {
+	var devices []*model.Device
+	err := r.db.WithContext(ctx).
+		Where("connection_status = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", model.DeviceConnectionOnline, staleBefore).
+		Order("last_seen_at").
+		Limit(limit).
+		Find(&devices).Error
+	return devices, err
+}
+
+// GetStatusEvents 获取设备连接状态变化记录，按时间倒序
+func (r *DeviceRepository) GetStatusEvents(ctx context.Context, deviceID string, limit int) ([]*model.DeviceStatusEvent, error) {
+	var events []*model.DeviceStatusEvent
+	err := r.db.WithContext(ctx).Where("device_id = ?", deviceID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error
+	return events, err
 }
 
 // Delete 解绑设备
@@ -88,3 +196,10 @@ func (r *DeviceRepository) GetUserByDevice(ctx context.Context, deviceID string)
 	}
 	return device.UserID, nil
 }
+
+// UpdateAPIKey 更新设备接入密钥摘要，旧密钥随之失效
+func (r *DeviceRepository) UpdateAPIKey(ctx context.Context, deviceID string, keyHash string) error {
+	return r.db.WithContext(ctx).Model(&model.Device{}).
+		Where("id = ?", deviceID).
+		Update("api_key_hash", keyHash).Error
+}
@@ -0,0 +1,65 @@
+package device
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// newDryRunRepository 只生成 SQL 不连接数据库的仓储，执行的语句依次写入 sqls
+func newDryRunRepository(t *testing.T, sqls *[]string) *DeviceRepository {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(tx *gorm.DB) { *sqls = append(*sqls, tx.Statement.SQL.String()) }
+	if err := db.Callback().Update().After("gorm:update").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return NewDeviceRepository(db)
+}
+
+func TestUpdateSettingsOnlyWritesSettings(t *testing.T) {
+	var sqls []string
+	repo := newDryRunRepository(t, &sqls)
+	ctx := context.Background()
+
+	name := "watch"
+	enabled := false
+	threshold := 15
+	if err := repo.UpdateSettings(ctx, "dev-1", &name, &enabled, &threshold); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateSettings(ctx, "dev-1", nil, nil, &threshold); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateSettings(ctx, "dev-1", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(sqls) != 2 {
+		t.Fatalf("executed %d statements, want 2: %q", len(sqls), sqls)
+	}
+
+	for _, sql := range sqls {
+		for _, column := range []string{"connection_status", "last_seen_at", "status_changed_at", "battery_level", "user_id"} {
+			if strings.Contains(sql, column) {
+				t.Fatalf("settings update writes %s: %s", column, sql)
+			}
+		}
+	}
+	for _, column := range []string{"`name`", "`notification_enabled`", "`low_battery_threshold`"} {
+		if !strings.Contains(sqls[0], column) {
+			t.Fatalf("settings update misses %s: %s", column, sqls[0])
+		}
+	}
+	if strings.Contains(sqls[1], "`name`") || !strings.Contains(sqls[1], "`low_battery_threshold`") {
+		t.Fatalf("partial settings update: %s", sqls[1])
+	}
+}
@@ -18,6 +18,7 @@ type IFriendRepository interface {
 	CreateFriend(ctx context.Context, friend *model.Friend) error
 	GetFriends(ctx context.Context, userID int64, status string) ([]*model.Friend, error)
 	GetFriend(ctx context.Context, userID, friendID int64) (*model.Friend, error)
+	GetFriendsOf(ctx context.Context, friendID int64, status string) ([]*model.Friend, error)
 	RemoveFriend(ctx context.Context, userID, friendID int64) error
 	AreFriends(ctx context.Context, userID, friendID int64) (bool, error)
 }
@@ -100,6 +101,18 @@ func (r *FriendRepository) GetFriends(ctx context.Context, userID int64, status
 	return friends, err
 }
 
+// GetFriendsOf 获取把该用户加为好友的关系记录（反向关系），
+// 记录中的 SharingStatus 即对方向该用户共享位置的状态
+func (r *FriendRepository) GetFriendsOf(ctx context.Context, friendID int64, status string) ([]*model.Friend, error) {
+	var friends []*model.Friend
+	query := r.db.WithContext(ctx).Where("friend_id = ?", friendID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&friends).Error
+	return friends, err
+}
+
 // GetFriend 获取特定好友关系
 func (r *FriendRepository) GetFriend(ctx context.Context, userID, friendID int64) (*model.Friend, error) {
 	var friend model.Friend
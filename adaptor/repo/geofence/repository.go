@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"app/adaptor/repo/model"
+	"app/utils/logger"
 )
 
 // IGeofenceRepository 地理围栏仓储接口
@@ -47,6 +49,9 @@ func (r *GeofenceRepository) Get(ctx context.Context, geofenceID int64) (*model.
 		return nil, err
 	}
 	geofence.ScanCenter()
+	if err := geofence.ScanGeometry(); err != nil {
+		return nil, err
+	}
 	return &geofence, nil
 }
 
@@ -59,6 +64,9 @@ func (r *GeofenceRepository) ListByUser(ctx context.Context, userID int64) ([]*m
 	}
 	for _, g := range geofences {
 		g.ScanCenter()
+		if err := g.ScanGeometry(); err != nil {
+			return nil, err
+		}
 	}
 	return geofences, nil
 }
@@ -74,16 +82,24 @@ func (r *GeofenceRepository) Delete(ctx context.Context, geofenceID int64) error
 }
 
 // GetActiveGeofences 获取用户活跃的地理围栏
+// 几何数据无法解析的围栏被跳过，避免按空多边形判定而误报离开事件
 func (r *GeofenceRepository) GetActiveGeofences(ctx context.Context, userID int64) ([]*model.Geofence, error) {
 	var geofences []*model.Geofence
 	err := r.db.WithContext(ctx).Where("user_id = ? AND is_active = ?", userID, true).Find(&geofences).Error
 	if err != nil {
 		return nil, err
 	}
+	valid := geofences[:0]
 	for _, g := range geofences {
 		g.ScanCenter()
+		if err := g.ScanGeometry(); err != nil {
+			logger.Warn("skip geofence with invalid geometry",
+				zap.Int64("geofence_id", g.ID), zap.Int64("user_id", g.UserID), zap.Error(err))
+			continue
+		}
+		valid = append(valid, g)
 	}
-	return geofences, nil
+	return valid, nil
 }
 
 // CheckPointInGeofences 检查点是否在围栏内
@@ -0,0 +1,96 @@
+package geofence
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"app/adaptor/repo/model"
+	"app/utils/geo"
+)
+
+// newDryRunRepository 只生成 SQL 不连接数据库的仓储，执行的语句依次写入 stmts
+func newDryRunRepository(t *testing.T, stmts *[]*gorm.Statement) *GeofenceRepository {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(tx *gorm.DB) { *stmts = append(*stmts, tx.Statement) }
+	if err := db.Callback().Create().After("gorm:create").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return NewGeofenceRepository(db)
+}
+
+// geometryVar 取出最后一条语句中 geometry 列对应的参数
+func geometryVar(t *testing.T, stmts []*gorm.Statement) interface{} {
+	t.Helper()
+	if len(stmts) == 0 {
+		t.Fatal("no statement executed")
+	}
+	stmt := stmts[len(stmts)-1]
+	sql := stmt.SQL.String()
+	var columns []string
+	switch {
+	case strings.HasPrefix(sql, "INSERT"):
+		list := sql[strings.Index(sql, "(")+1 : strings.Index(sql, ")")]
+		columns = strings.Split(list, ",")
+	case strings.HasPrefix(sql, "UPDATE"):
+		list := sql[strings.Index(sql, " SET ")+5 : strings.Index(sql, " WHERE ")]
+		for _, assign := range strings.Split(list, ",") {
+			columns = append(columns, strings.SplitN(assign, "=", 2)[0])
+		}
+	}
+	for i, c := range columns {
+		if strings.Trim(c, "` ") == "geometry" {
+			return stmt.Vars[i]
+		}
+	}
+	t.Fatalf("geometry column not found in %s", sql)
+	return nil
+}
+
+func TestCircleGeofenceStoresNullGeometry(t *testing.T) {
+	var stmts []*gorm.Statement
+	repo := newDryRunRepository(t, &stmts)
+	ctx := context.Background()
+
+	circle := &model.Geofence{UserID: 1, Name: "home"}
+	circle.SetCircle(116.39, 39.9, 200)
+	if err := repo.Create(ctx, circle); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := geometryVar(t, stmts).(*string); !ok || v != nil {
+		t.Fatalf("insert geometry = %#v, want NULL", geometryVar(t, stmts))
+	}
+
+	// 多边形改为圆形后同样写入 NULL
+	fence := &model.Geofence{ID: 7, UserID: 1, Name: "park"}
+	square := geo.MultiPolygon{{{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 1}, {Lon: 0, Lat: 0}}}}
+	if err := fence.SetPolygons(square); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, fence); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := geometryVar(t, stmts).(*string); !ok || v == nil || !strings.Contains(*v, "Polygon") {
+		t.Fatalf("polygon geometry = %#v, want GeoJSON", geometryVar(t, stmts))
+	}
+	fence.SetCircle(116.39, 39.9, 200)
+	if err := repo.Update(ctx, fence); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := geometryVar(t, stmts).(*string); !ok || v != nil {
+		t.Fatalf("update geometry = %#v, want NULL", geometryVar(t, stmts))
+	}
+}
@@ -13,13 +13,18 @@ import (
 
 // ILocationRepository 位置仓储接口
 type ILocationRepository interface {
-	CreateUserLocation(ctx context.Context, loc *model.UserLocation) error
-	BatchCreateUserLocations(ctx context.Context, locs []*model.UserLocation) error
+	CreateUserLocation(ctx context.Context, loc *model.UserLocation) (bool, error)
+	BatchCreateUserLocations(ctx context.Context, locs []*model.UserLocation) ([]*model.UserLocation, error)
 	GetLatestUserLocation(ctx context.Context, userID int64) (*model.UserLocation, error)
 	GetUserLocationHistory(ctx context.Context, userID int64, startTime, endTime time.Time, limit, offset int) ([]*model.UserLocation, error)
 	CreateDeviceLocation(ctx context.Context, loc *model.DeviceLocation) error
 	GetLatestDeviceLocation(ctx context.Context, deviceID string) (*model.DeviceLocation, error)
 	DeleteUserLocations(ctx context.Context, userID int64) error
+	CreateRejectedLocations(ctx context.Context, locs []*model.RejectedLocation) error
+	GetUserTrajectory(ctx context.Context, userID int64, startTime, endTime time.Time, limit int) ([]*model.UserLocation, error)
+	StreamUserLocations(ctx context.Context, userID int64, startTime, endTime time.Time, fn func(*model.UserLocation) error) error
+	GetUserLocationTimes(ctx context.Context, userID int64, startTime, endTime time.Time) ([]time.Time, error)
+	GetExistingPointIDs(ctx context.Context, userID int64, pointIDs []string) (map[string]bool, error)
 }
 
 // LocationRepository 位置仓储实现
@@ -32,23 +37,95 @@ func NewLocationRepository(db *gorm.DB) *LocationRepository {
 	return &LocationRepository{db: db}
 }
 
-// CreateUserLocation 创建用户位置
-func (r *LocationRepository) CreateUserLocation(ctx context.Context, loc *model.UserLocation) error {
-	return r.db.WithContext(ctx).Create(loc).Error
+// CreateUserLocation 创建用户位置，同一用户的 PointID 已存在时不写入并返回 false
+func (r *LocationRepository) CreateUserLocation(ctx context.Context, loc *model.UserLocation) (bool, error) {
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(loc)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// userLocationBatchSize 批量写入位置时每条 INSERT 的行数
+const userLocationBatchSize = 100
+
+// BatchCreateUserLocations 批量创建用户位置，同一用户的 PointID 已存在的行被跳过，返回实际写入的行
+// 调用方应先通过 GetExistingPointIDs 过滤，唯一索引保证并发重试时不会重复写入；
+// 某批有行因并发写入被跳过时，回滚该批后逐行写入，以确定哪些行被跳过
+func (r *LocationRepository) BatchCreateUserLocations(ctx context.Context, locs []*model.UserLocation) ([]*model.UserLocation, error) {
+	if len(locs) == 0 {
+		return nil, nil
+	}
+	created := make([]*model.UserLocation, 0, len(locs))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(locs); start += userLocationBatchSize {
+			batch := locs[start:min(start+userLocationBatchSize, len(locs))]
+			if err := tx.SavePoint("user_locations_batch").Error; err != nil {
+				return err
+			}
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(batch)
+			if res.Error != nil {
+				return res.Error
+			}
+			if int(res.RowsAffected) == len(batch) {
+				created = append(created, batch...)
+				continue
+			}
+
+			if err := tx.RollbackTo("user_locations_batch").Error; err != nil {
+				return err
+			}
+			for _, loc := range batch {
+				// 回滚前回填的自增ID已失效
+				loc.ID = 0
+				res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(loc)
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected > 0 {
+					created = append(created, loc)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetExistingPointIDs 获取用户已保存的位置点ID
+func (r *LocationRepository) GetExistingPointIDs(ctx context.Context, userID int64, pointIDs []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(pointIDs))
+	if len(pointIDs) == 0 {
+		return result, nil
+	}
+	var existing []string
+	err := r.db.WithContext(ctx).Model(&model.UserLocation{}).
+		Where("user_id = ? AND point_id IN ?", userID, pointIDs).
+		Pluck("point_id", &existing).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		result[id] = true
+	}
+	return result, nil
 }
 
-// BatchCreateUserLocations 批量创建用户位置
-func (r *LocationRepository) BatchCreateUserLocations(ctx context.Context, locs []*model.UserLocation) error {
+// CreateRejectedLocations 记录被拒绝的位置点
+func (r *LocationRepository) CreateRejectedLocations(ctx context.Context, locs []*model.RejectedLocation) error {
 	if len(locs) == 0 {
 		return nil
 	}
-	return r.db.WithContext(ctx).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, DoNothing: true}).CreateInBatches(locs, 100).Error
+	return r.db.WithContext(ctx).CreateInBatches(locs, 100).Error
 }
 
 // GetLatestUserLocation 获取用户最新位置
 func (r *LocationRepository) GetLatestUserLocation(ctx context.Context, userID int64) (*model.UserLocation, error) {
 	var loc model.UserLocation
-	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&loc).Error
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("recorded_at DESC").First(&loc).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
@@ -59,17 +136,17 @@ func (r *LocationRepository) GetLatestUserLocation(ctx context.Context, userID i
 	return &loc, nil
 }
 
-// GetUserLocationHistory 获取用户位置历史
+// GetUserLocationHistory 获取用户位置历史，按定位时间倒序
 func (r *LocationRepository) GetUserLocationHistory(ctx context.Context, userID int64, startTime, endTime time.Time, limit, offset int) ([]*model.UserLocation, error) {
 	var locs []*model.UserLocation
-	query := r.db.WithContext(ctx).Where("user_id = ? AND created_at BETWEEN ? AND ?", userID, startTime, endTime)
+	query := r.db.WithContext(ctx).Where("user_id = ? AND recorded_at BETWEEN ? AND ?", userID, startTime, endTime)
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
 	if offset > 0 {
 		query = query.Offset(offset)
 	}
-	err := query.Order("created_at DESC").Find(&locs).Error
+	err := query.Order("recorded_at DESC").Find(&locs).Error
 	if err != nil {
 		return nil, err
 	}
@@ -80,15 +157,64 @@ func (r *LocationRepository) GetUserLocationHistory(ctx context.Context, userID
 	return locs, nil
 }
 
+// GetUserTrajectory 按时间正序获取时间段内的用户位置，用于轨迹计算
+func (r *LocationRepository) GetUserTrajectory(ctx context.Context, userID int64, startTime, endTime time.Time, limit int) ([]*model.UserLocation, error) {
+	var locs []*model.UserLocation
+	query := r.db.WithContext(ctx).Where("user_id = ? AND recorded_at BETWEEN ? AND ?", userID, startTime, endTime)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("recorded_at ASC").Find(&locs).Error; err != nil {
+		return nil, err
+	}
+	for _, loc := range locs {
+		loc.ScanLocation()
+	}
+	return locs, nil
+}
+
+// StreamUserLocations 按时间正序逐行读取时间段内的用户位置，避免一次性加载到内存
+// fn 返回错误时停止读取并返回该错误
+func (r *LocationRepository) StreamUserLocations(ctx context.Context, userID int64, startTime, endTime time.Time, fn func(*model.UserLocation) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&model.UserLocation{}).
+		Where("user_id = ? AND recorded_at BETWEEN ? AND ?", userID, startTime, endTime).
+		Order("recorded_at ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var loc model.UserLocation
+		if err := r.db.ScanRows(rows, &loc); err != nil {
+			return err
+		}
+		loc.ScanLocation()
+		if err := fn(&loc); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetUserLocationTimes 获取时间段内已有位置的时间，用于导入去重
+func (r *LocationRepository) GetUserLocationTimes(ctx context.Context, userID int64, startTime, endTime time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.db.WithContext(ctx).Model(&model.UserLocation{}).
+		Where("user_id = ? AND recorded_at BETWEEN ? AND ?", userID, startTime, endTime).
+		Pluck("recorded_at", &times).Error
+	return times, err
+}
+
 // CreateDeviceLocation 创建设备位置
 func (r *LocationRepository) CreateDeviceLocation(ctx context.Context, loc *model.DeviceLocation) error {
 	return r.db.WithContext(ctx).Create(loc).Error
 }
 
-// GetLatestDeviceLocation 获取设备最新位置
+// GetLatestDeviceLocation 获取设备定位时间最新的位置
 func (r *LocationRepository) GetLatestDeviceLocation(ctx context.Context, deviceID string) (*model.DeviceLocation, error) {
 	var loc model.DeviceLocation
-	err := r.db.WithContext(ctx).Where("device_id = ?", deviceID).Order("created_at DESC").First(&loc).Error
+	err := r.db.WithContext(ctx).Where("device_id = ?", deviceID).Order("recorded_at DESC, id DESC").First(&loc).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
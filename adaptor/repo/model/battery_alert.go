@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// 电量告警类型
+const (
+	BatteryAlertLow        = "low_battery" // 电量低于阈值
+	BatteryAlertRapidDrain = "rapid_drain" // 耗电过快
+)
+
+// 未设置时的低电量阈值（百分比）
+const DefaultLowBatteryThreshold = 20
+
+// BatteryAlert 电量告警记录，每个放电周期内同类告警只产生一次
+type BatteryAlert struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       int64     `gorm:"not null;index:idx_battery_alerts_user,priority:1" json:"user_id"` // 接收告警的用户：用户本人或设备绑定者
+	EntityType   string    `gorm:"type:varchar(20);not null" json:"entity_type"`                     // user, device
+	EntityID     string    `gorm:"type:varchar(64);not null" json:"entity_id"`
+	Type         string    `gorm:"type:varchar(20);not null" json:"type"`
+	BatteryLevel int       `gorm:"not null" json:"battery_level"`
+	Threshold    int       `gorm:"not null;default:0" json:"threshold"`  // 低电量阈值，仅 low_battery
+	DrainRate    float64   `gorm:"not null;default:0" json:"drain_rate"` // 耗电速度（百分比/小时），仅 rapid_drain
+	CreatedAt    time.Time `gorm:"autoCreateTime;index:idx_battery_alerts_user,priority:2" json:"created_at"`
+}
+
+func (*BatteryAlert) TableName() string {
+	return "battery_alerts"
+}
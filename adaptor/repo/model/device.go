@@ -13,6 +13,10 @@ type Device struct {
 	BatteryLevel        int                     `gorm:"type:int;default:0" json:"battery_level"`
 	ConnectionStatus    DeviceConnectionStatus  `gorm:"type:varchar(20);default:'unknown'" json:"connection_status"`
 	NotificationEnabled bool                    `gorm:"default:true" json:"notification_enabled"`
+	LowBatteryThreshold int                     `gorm:"type:int;default:20" json:"low_battery_threshold"` // 低电量告警阈值，0 表示关闭
+	APIKeyHash          string                  `gorm:"type:char(64);default:''" json:"-"`
+	LastSeenAt          *time.Time              `gorm:"index" json:"last_seen_at"`       // 最近一次位置上报或心跳时间
+	StatusChangedAt     *time.Time              `json:"status_changed_at"`               // 连接状态最近一次变化时间
 	CreatedAt           time.Time               `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt           time.Time               `gorm:"autoUpdateTime" json:"updated_at"`
 }
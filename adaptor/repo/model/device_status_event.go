@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// 设备连接状态变化原因
+const (
+	DeviceStatusReasonReport    = "report"    // 位置上报
+	DeviceStatusReasonHeartbeat = "heartbeat" // 心跳
+	DeviceStatusReasonTimeout   = "timeout"   // 超时未上报
+	DeviceStatusReasonManual    = "manual"    // 客户端更新状态
+)
+
+// DeviceStatusEvent 设备连接状态变化记录
+type DeviceStatusEvent struct {
+	ID         int64                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeviceID   string                 `gorm:"type:varchar(64);not null;index:idx_device_status_events_device,priority:1" json:"device_id"`
+	UserID     int64                  `gorm:"not null" json:"user_id"` // 变化时的设备绑定者
+	FromStatus DeviceConnectionStatus `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus   DeviceConnectionStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	Reason     string                 `gorm:"type:varchar(20);not null" json:"reason"`
+	LastSeenAt *time.Time             `json:"last_seen_at"`
+	CreatedAt  time.Time              `gorm:"autoCreateTime;index:idx_device_status_events_device,priority:2" json:"created_at"`
+}
+
+func (*DeviceStatusEvent) TableName() string {
+	return "device_status_events"
+}
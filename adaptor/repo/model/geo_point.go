@@ -0,0 +1,152 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SRIDWGS84 GPS 坐标使用的空间参考系
+const SRIDWGS84 = 4326
+
+const (
+	wkbPointType = 1
+	// 字节序标记 + 几何类型 + 两个坐标
+	wkbPointSize = 1 + 4 + 16
+	// MySQL 内部格式在 WKB 前加 4 字节小端 SRID
+	mysqlPointSize = 4 + wkbPointSize
+)
+
+// ErrInvalidGeoPoint 几何数据不是合法的点
+var ErrInvalidGeoPoint = errors.New("invalid geometry point")
+
+// GeoPoint 对应 MySQL POINT 列
+//
+// 写入时编码为 MySQL 内部格式（4 字节小端 SRID 4326 + 小端 WKB），
+// 读取时支持 MySQL 内部格式、WKB 和 WKT。内部格式与 WKB 中 X 为经度、Y 为纬度，
+// WKT 同样按 "POINT(经度 纬度)" 解析。
+type GeoPoint struct {
+	Lon float64
+	Lat float64
+}
+
+// Value 实现 driver.Valuer
+func (p GeoPoint) Value() (driver.Value, error) {
+	return p.MySQLBytes(), nil
+}
+
+// Scan 实现 sql.Scanner，NULL 解析为零值
+func (p *GeoPoint) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = GeoPoint{}
+		return nil
+	case []byte:
+		return p.decode(v)
+	case string:
+		return p.decode([]byte(v))
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrInvalidGeoPoint, src)
+	}
+}
+
+func (p *GeoPoint) decode(data []byte) error {
+	switch {
+	case len(data) == mysqlPointSize && (data[4] == 0 || data[4] == 1):
+		pt, err := ParseWKB(data[4:])
+		if err != nil {
+			return err
+		}
+		*p = pt
+		return nil
+	case len(data) == wkbPointSize && (data[0] == 0 || data[0] == 1):
+		pt, err := ParseWKB(data)
+		if err != nil {
+			return err
+		}
+		*p = pt
+		return nil
+	default:
+		pt, err := ParsePointWKT(string(data))
+		if err != nil {
+			return err
+		}
+		*p = pt
+		return nil
+	}
+}
+
+// MySQLBytes 编码为 MySQL 内部格式
+func (p GeoPoint) MySQLBytes() []byte {
+	buf := make([]byte, mysqlPointSize)
+	binary.LittleEndian.PutUint32(buf, SRIDWGS84)
+	copy(buf[4:], p.WKB())
+	return buf
+}
+
+// WKB 编码为小端 WKB
+func (p GeoPoint) WKB() []byte {
+	buf := make([]byte, wkbPointSize)
+	buf[0] = 1
+	binary.LittleEndian.PutUint32(buf[1:], wkbPointType)
+	binary.LittleEndian.PutUint64(buf[5:], math.Float64bits(p.Lon))
+	binary.LittleEndian.PutUint64(buf[13:], math.Float64bits(p.Lat))
+	return buf
+}
+
+// WKT 编码为 "POINT(经度 纬度)"，坐标使用能精确还原的最短表示
+func (p GeoPoint) WKT() string {
+	return "POINT(" + strconv.FormatFloat(p.Lon, 'g', -1, 64) + " " + strconv.FormatFloat(p.Lat, 'g', -1, 64) + ")"
+}
+
+// ParseWKB 解析 WKB 点，支持大端和小端
+func ParseWKB(data []byte) (GeoPoint, error) {
+	if len(data) != wkbPointSize {
+		return GeoPoint{}, fmt.Errorf("%w: wkb length %d", ErrInvalidGeoPoint, len(data))
+	}
+	var order binary.ByteOrder
+	switch data[0] {
+	case 0:
+		order = binary.BigEndian
+	case 1:
+		order = binary.LittleEndian
+	default:
+		return GeoPoint{}, fmt.Errorf("%w: byte order %d", ErrInvalidGeoPoint, data[0])
+	}
+	if t := order.Uint32(data[1:]); t != wkbPointType {
+		return GeoPoint{}, fmt.Errorf("%w: geometry type %d", ErrInvalidGeoPoint, t)
+	}
+	return GeoPoint{
+		Lon: math.Float64frombits(order.Uint64(data[5:])),
+		Lat: math.Float64frombits(order.Uint64(data[13:])),
+	}, nil
+}
+
+// ParsePointWKT 解析 "POINT(经度 纬度)"，不区分大小写，允许多余空白
+func ParsePointWKT(wkt string) (GeoPoint, error) {
+	s := strings.TrimSpace(wkt)
+	if len(s) < 5 || !strings.EqualFold(s[:5], "POINT") {
+		return GeoPoint{}, fmt.Errorf("%w: %q", ErrInvalidGeoPoint, wkt)
+	}
+	s = strings.TrimSpace(s[5:])
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return GeoPoint{}, fmt.Errorf("%w: %q", ErrInvalidGeoPoint, wkt)
+	}
+	fields := strings.Fields(s[1 : len(s)-1])
+	if len(fields) != 2 {
+		return GeoPoint{}, fmt.Errorf("%w: %q", ErrInvalidGeoPoint, wkt)
+	}
+	lon, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return GeoPoint{}, fmt.Errorf("%w: %q", ErrInvalidGeoPoint, wkt)
+	}
+	lat, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return GeoPoint{}, fmt.Errorf("%w: %q", ErrInvalidGeoPoint, wkt)
+	}
+	return GeoPoint{Lon: lon, Lat: lat}, nil
+}
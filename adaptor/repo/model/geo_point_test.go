@@ -0,0 +1,172 @@
+package model
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// bigEndianWKB 按大端编码 WKB 点
+func bigEndianWKB(p GeoPoint) []byte {
+	buf := make([]byte, wkbPointSize)
+	buf[0] = 0
+	binary.BigEndian.PutUint32(buf[1:], wkbPointType)
+	binary.BigEndian.PutUint64(buf[5:], math.Float64bits(p.Lon))
+	binary.BigEndian.PutUint64(buf[13:], math.Float64bits(p.Lat))
+	return buf
+}
+
+// mysqlBigEndian MySQL 内部格式中的 WKB 部分使用大端
+func mysqlBigEndian(p GeoPoint) []byte {
+	buf := make([]byte, 4, mysqlPointSize)
+	binary.LittleEndian.PutUint32(buf, SRIDWGS84)
+	return append(buf, bigEndianWKB(p)...)
+}
+
+func sameFloat(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+func samePoint(a, b GeoPoint) bool {
+	return sameFloat(a.Lon, b.Lon) && sameFloat(a.Lat, b.Lat)
+}
+
+// encodings 同一个点的所有可读取编码
+func encodings(p GeoPoint) map[string][]byte {
+	return map[string][]byte{
+		"mysql":            p.MySQLBytes(),
+		"mysql big endian": mysqlBigEndian(p),
+		"wkb":              p.WKB(),
+		"wkb big endian":   bigEndianWKB(p),
+		"wkt":              []byte(p.WKT()),
+	}
+}
+
+func TestGeoPointScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+		want GeoPoint
+	}{
+		{"nil", nil, GeoPoint{}},
+		{"wkt string", "POINT(116.397 39.909)", GeoPoint{Lon: 116.397, Lat: 39.909}},
+		{"wkt lower case with spaces", []byte("  point ( -122.4194   37.7749 ) "), GeoPoint{Lon: -122.4194, Lat: 37.7749}},
+		{"mysql bytes", GeoPoint{Lon: 121.4737, Lat: 31.2304}.MySQLBytes(), GeoPoint{Lon: 121.4737, Lat: 31.2304}},
+		{"big endian wkb", bigEndianWKB(GeoPoint{Lon: -0.1276, Lat: 51.5072}), GeoPoint{Lon: -0.1276, Lat: 51.5072}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := GeoPoint{Lon: 1, Lat: 1}
+			if err := p.Scan(tt.src); err != nil {
+				t.Fatal(err)
+			}
+			if p != tt.want {
+				t.Fatalf("got %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoPointScanInvalid(t *testing.T) {
+	p := GeoPoint{Lon: 116.397, Lat: 39.909}
+	wrongType := p.WKB()
+	binary.LittleEndian.PutUint32(wrongType[1:], 2)
+	badOrder := p.MySQLBytes()
+	badOrder[4] = 2
+
+	tests := []struct {
+		name string
+		src  interface{}
+	}{
+		{"unsupported type", 42},
+		{"empty", []byte{}},
+		{"wkb wrong geometry type", wrongType},
+		{"mysql bad byte order", badOrder},
+		{"wkt missing coordinate", "POINT(116.397)"},
+		{"wkt extra coordinate", "POINT(1 2 3)"},
+		{"wkt not a number", "POINT(a b)"},
+		{"wkt unclosed", "POINT(1 2"},
+		{"wkt other geometry", "LINESTRING(1 2, 3 4)"},
+		{"srid prefixed wkt", "SRID=4326;POINT(1 2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p
+			err := got.Scan(tt.src)
+			if !errors.Is(err, ErrInvalidGeoPoint) {
+				t.Fatalf("err = %v, want ErrInvalidGeoPoint", err)
+			}
+			if got != p {
+				t.Fatalf("failed scan modified point: %+v", got)
+			}
+		})
+	}
+}
+
+func TestGeoPointScanTruncated(t *testing.T) {
+	p := GeoPoint{Lon: 116.397, Lat: 39.909}
+	for name, data := range encodings(p) {
+		if name == "wkt" {
+			continue
+		}
+		for n := 0; n < len(data); n++ {
+			var got GeoPoint
+			if err := got.Scan(data[:n]); err == nil {
+				t.Fatalf("%s truncated to %d bytes: expected error, got %+v", name, n, got)
+			}
+		}
+	}
+}
+
+func FuzzGeoPoint(f *testing.F) {
+	seeds := []GeoPoint{
+		{},
+		{Lon: 116.397, Lat: 39.909},
+		{Lon: -180, Lat: -90},
+		{Lon: 180, Lat: 90},
+		{Lon: math.Inf(1), Lat: math.NaN()},
+	}
+	for _, p := range seeds {
+		for _, data := range encodings(p) {
+			f.Add(data, p.Lon, p.Lat)
+			f.Add(data[:len(data)/2], p.Lon, p.Lat)
+		}
+	}
+	f.Add([]byte("POINT(1 2) trailing"), 1.0, 2.0)
+
+	f.Fuzz(func(t *testing.T, data []byte, lon, lat float64) {
+		// 任意输入都不能 panic，失败时返回 ErrInvalidGeoPoint
+		var p GeoPoint
+		if err := p.Scan(data); err != nil {
+			if !errors.Is(err, ErrInvalidGeoPoint) {
+				t.Fatalf("unexpected error %v", err)
+			}
+		} else {
+			// 成功读取的点经 Value 写回后再读取保持不变
+			v, err := p.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again GeoPoint
+			if err := again.Scan(v); err != nil {
+				t.Fatalf("rescan %+v: %v", p, err)
+			}
+			if !samePoint(p, again) {
+				t.Fatalf("rescan %+v got %+v", p, again)
+			}
+		}
+
+		// 所有编码都能还原坐标
+		want := GeoPoint{Lon: lon, Lat: lat}
+		for name, enc := range encodings(want) {
+			var got GeoPoint
+			if err := got.Scan(enc); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !samePoint(got, want) {
+				t.Fatalf("%s: got %+v, want %+v", name, got, want)
+			}
+		}
+	})
+}
@@ -1,62 +1,136 @@
 package model
 
 import (
+	"fmt"
 	"time"
+
+	"app/utils/geo"
+)
+
+// 围栏事件实体类型
+const (
+	GeofenceEntityUser   = "user"
+	GeofenceEntityDevice = "device"
+)
+
+// 围栏事件类型
+const (
+	GeofenceEventEnter = "enter"
+	GeofenceEventExit  = "exit"
+)
+
+// GeofenceShape 围栏形状
+type GeofenceShape string
+
+const (
+	GeofenceShapeCircle       GeofenceShape = "circle"
+	GeofenceShapePolygon      GeofenceShape = "polygon"
+	GeofenceShapeMultiPolygon GeofenceShape = "multipolygon"
 )
 
 // Geofence 地理围栏模型
+// 多边形围栏的 Center/RadiusMeters 保存外接圆，用于粗筛
 type Geofence struct {
-	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID        int64     `gorm:"not null;index" json:"user_id"`
-	Name          string    `gorm:"type:varchar(100);not null" json:"name"`
-	Center        string    `gorm:"type:point;not null" json:"-"`
-	CenterLon     float64   `gorm:"-" json:"center_lon"`
-	CenterLat     float64   `gorm:"-" json:"center_lat"`
-	RadiusMeters  float64   `gorm:"type:float;not null" json:"radius_meters"`
-	NotifyOnEnter bool      `gorm:"default:true" json:"notify_on_enter"`
-	NotifyOnExit  bool      `gorm:"default:true" json:"notify_on_exit"`
-	IsActive      bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID            int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        int64            `gorm:"not null;index" json:"user_id"`
+	Name          string           `gorm:"type:varchar(100);not null" json:"name"`
+	ShapeType     GeofenceShape    `gorm:"type:varchar(20);not null;default:'circle'" json:"shape_type"`
+	Geometry      *string          `gorm:"type:json" json:"-"` // GeoJSON，仅多边形围栏，圆形围栏为 NULL
+	Polygons      geo.MultiPolygon `gorm:"-" json:"-"`
+	Center        GeoPoint         `gorm:"type:point;not null" json:"-"`
+	CenterLon     float64          `gorm:"-" json:"center_lon"`
+	CenterLat     float64          `gorm:"-" json:"center_lat"`
+	RadiusMeters  float64          `gorm:"type:float;not null" json:"radius_meters"`
+	NotifyOnEnter bool             `gorm:"default:true" json:"notify_on_enter"`
+	NotifyOnExit  bool             `gorm:"default:true" json:"notify_on_exit"`
+	IsActive      bool             `gorm:"default:true" json:"is_active"`
+	CreatedAt     time.Time        `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time        `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (*Geofence) TableName() string {
 	return "geofences"
 }
 
-// ScanCenter 解析围栏中心坐标
-func (g *Geofence) ScanCenter() error {
-	if g.Center == "" {
+// ScanCenter 从 POINT 列填充围栏中心坐标
+func (g *Geofence) ScanCenter() {
+	g.CenterLon = g.Center.Lon
+	g.CenterLat = g.Center.Lat
+}
+
+// ScanGeometry 解析多边形围栏的几何数据
+func (g *Geofence) ScanGeometry() error {
+	if !g.IsPolygon() {
 		return nil
 	}
-	var lon, lat float64
-	ok, err := scanPoint(g.Center, &lon, &lat)
-	if !ok || err != nil {
+	if g.Geometry == nil {
+		return fmt.Errorf("geofence %d: polygon without geometry", g.ID)
+	}
+	mp, err := geo.ParseGeoJSON([]byte(*g.Geometry))
+	if err != nil {
+		return fmt.Errorf("geofence %d: %w", g.ID, err)
+	}
+	g.Polygons = mp
+	return nil
+}
+
+// SetPolygons 设置多边形围栏，同时以外接圆更新中心和半径
+func (g *Geofence) SetPolygons(mp geo.MultiPolygon) error {
+	data, err := mp.GeoJSON()
+	if err != nil {
 		return err
 	}
-	g.CenterLon = lon
-	g.CenterLat = lat
+	g.ShapeType = GeofenceShapePolygon
+	if len(mp) > 1 {
+		g.ShapeType = GeofenceShapeMultiPolygon
+	}
+	geometry := string(data)
+	g.Geometry = &geometry
+	g.Polygons = mp
+
+	center, radius := mp.BoundingCircle()
+	g.SetCenter(center.Lon, center.Lat)
+	g.RadiusMeters = radius
 	return nil
 }
 
+// SetCircle 设置圆形围栏，清除多边形几何数据
+func (g *Geofence) SetCircle(lon, lat, radiusMeters float64) {
+	g.ShapeType = GeofenceShapeCircle
+	g.Geometry = nil
+	g.Polygons = nil
+	g.SetCenter(lon, lat)
+	g.RadiusMeters = radiusMeters
+}
+
+// IsPolygon 是否为多边形围栏
+func (g *Geofence) IsPolygon() bool {
+	return g.ShapeType == GeofenceShapePolygon || g.ShapeType == GeofenceShapeMultiPolygon
+}
+
 // SetCenter 设置围栏中心
 func (g *Geofence) SetCenter(lon, lat float64) {
-	g.Center = "POINT(" + formatFloat(lon) + " " + formatFloat(lat) + ")"
+	g.Center = GeoPoint{Lon: lon, Lat: lat}
 	g.CenterLon = lon
 	g.CenterLat = lat
 }
 
 // GeofenceEvent 地理围栏事件模型
 type GeofenceEvent struct {
-	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	GeofenceID  int64     `gorm:"not null;index" json:"geofence_id"`
-	EntityType  string    `gorm:"type:varchar(20);not null" json:"entity_type"` // "user" or "device"
-	EntityID    int64     `gorm:"not null" json:"entity_id"`
-	EventType   string    `gorm:"type:varchar(20);not null" json:"event_type"` // "enter" or "exit"
-	Location    string    `gorm:"type:point" json:"-"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	GeofenceID int64     `gorm:"not null;index" json:"geofence_id"`
+	EntityType string    `gorm:"type:varchar(20);not null" json:"entity_type"` // "user" or "device"
+	EntityID   string    `gorm:"type:varchar(64);not null" json:"entity_id"` // 用户ID或设备ID
+	EventType  string    `gorm:"type:varchar(20);not null" json:"event_type"` // "enter" or "exit"
+	Location   *GeoPoint `gorm:"type:point" json:"-"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
 
 func (*GeofenceEvent) TableName() string {
 	return "geofence_events"
 }
+
+// SetLocation 设置事件发生位置
+func (e *GeofenceEvent) SetLocation(lon, lat float64) {
+	e.Location = &GeoPoint{Lon: lon, Lat: lat}
+}
@@ -0,0 +1,33 @@
+package model
+
+import "testing"
+
+func TestGeofenceScanGeometry(t *testing.T) {
+	corrupt := `{"type":"Polygon","coordinates":[[[0,0],[1,0]]]}`
+	valid := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+
+	tests := []struct {
+		name     string
+		shape    GeofenceShape
+		geometry *string
+		wantErr  bool
+		polygons int
+	}{
+		{"circle", GeofenceShapeCircle, nil, false, 0},
+		{"polygon", GeofenceShapePolygon, &valid, false, 1},
+		{"polygon without geometry", GeofenceShapePolygon, nil, true, 0},
+		{"corrupt polygon", GeofenceShapePolygon, &corrupt, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Geofence{ShapeType: tt.shape, Geometry: tt.geometry}
+			err := g.ScanGeometry()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(g.Polygons) != tt.polygons {
+				t.Fatalf("polygons = %d, want %d", len(g.Polygons), tt.polygons)
+			}
+		})
+	}
+}
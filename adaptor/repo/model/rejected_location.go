@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// RejectReason 位置点被拒绝的原因
+type RejectReason string
+
+const (
+	RejectReasonInvalidCoordinate RejectReason = "invalid_coordinate" // 经纬度超出范围
+	RejectReasonNullIsland        RejectReason = "null_island"        // (0,0) 坐标，通常为定位失败的默认值
+	RejectReasonInvalidAccuracy   RejectReason = "invalid_accuracy"   // 精度为负数或非法值
+	RejectReasonImpossibleSpeed   RejectReason = "impossible_speed"   // 与上一个定位点相比速度不可能达到
+	RejectReasonInvalidTimestamp  RejectReason = "invalid_timestamp"  // 缺少定位时间或时间超出允许范围
+)
+
+// RejectedLocation 被拒绝的位置点，坐标可能非法，因此不使用 POINT 类型
+type RejectedLocation struct {
+	ID        int64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64        `gorm:"index" json:"user_id"`
+	DeviceID  string       `gorm:"type:varchar(64)" json:"device_id"`
+	Longitude float64      `json:"longitude"`
+	Latitude  float64      `json:"latitude"`
+	Accuracy  float64      `gorm:"type:float" json:"accuracy"`
+	Reason    RejectReason `gorm:"type:varchar(32);not null" json:"reason"`
+	Detail    string       `gorm:"type:varchar(255)" json:"detail"`
+	CreatedAt time.Time    `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (*RejectedLocation) TableName() string {
+	return "rejected_locations"
+}
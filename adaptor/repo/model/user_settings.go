@@ -23,14 +23,15 @@ const (
 
 // UserSettings 用户设置模型
 type UserSettings struct {
-	UserID        int64        `gorm:"primaryKey" json:"user_id"`
-	ShareLocation bool         `gorm:"default:true" json:"share_location"`
-	GhostMode     bool         `gorm:"default:false" json:"ghost_mode"`
-	SmartAlerts   bool         `gorm:"default:true" json:"smart_alerts"`
-	SOSAlerts     bool         `gorm:"default:true" json:"sos_alerts"`
-	MapStyle      MapStyle     `gorm:"type:varchar(20);default:'dark'" json:"map_style"`
-	DistanceUnit  DistanceUnit `gorm:"type:varchar(10);default:'km'" json:"distance_unit"`
-	UpdatedAt     time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+	UserID              int64        `gorm:"primaryKey" json:"user_id"`
+	ShareLocation       bool         `gorm:"default:true" json:"share_location"`
+	GhostMode           bool         `gorm:"default:false" json:"ghost_mode"`
+	SmartAlerts         bool         `gorm:"default:true" json:"smart_alerts"`
+	SOSAlerts           bool         `gorm:"default:true" json:"sos_alerts"`
+	MapStyle            MapStyle     `gorm:"type:varchar(20);default:'dark'" json:"map_style"`
+	DistanceUnit        DistanceUnit `gorm:"type:varchar(10);default:'km'" json:"distance_unit"`
+	LowBatteryThreshold int          `gorm:"type:int;default:20" json:"low_battery_threshold"` // 低电量告警阈值，0 表示关闭
+	UpdatedAt           time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (*UserSettings) TableName() string {
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Place 用户常去的地点，由多次停留聚类得到
+// 中心坐标为各次停留中心按停留次数的加权平均
+type Place struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        int64     `gorm:"not null;index" json:"user_id"`
+	Name          string    `gorm:"type:varchar(100)" json:"name"` // 用户命名，未命名时为空
+	Longitude     float64   `gorm:"not null" json:"longitude"`
+	Latitude      float64   `gorm:"not null" json:"latitude"`
+	VisitCount    int       `gorm:"not null;default:0" json:"visit_count"`
+	TotalDuration int64     `gorm:"not null;default:0" json:"total_duration"` // 累计停留时长（秒）
+	LastVisitAt   time.Time `json:"last_visit_at"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (*Place) TableName() string {
+	return "places"
+}
+
+// Visit 一次停留
+type Visit struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int64     `gorm:"not null;index" json:"user_id"`
+	PlaceID    int64     `gorm:"not null;index" json:"place_id"`
+	Longitude  float64   `gorm:"not null" json:"longitude"` // 停留点中心
+	Latitude   float64   `gorm:"not null" json:"latitude"`
+	ArrivedAt  time.Time `gorm:"not null" json:"arrived_at"`
+	DepartedAt time.Time `gorm:"not null" json:"departed_at"`
+	PointCount int       `gorm:"not null" json:"point_count"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (*Visit) TableName() string {
+	return "visits"
+}
+
+// Duration 停留时长
+func (v *Visit) Duration() time.Duration {
+	return v.DepartedAt.Sub(v.ArrivedAt)
+}
@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"app/adaptor/repo/model"
+)
+
+// ISettingsRepository 用户设置仓储接口
+type ISettingsRepository interface {
+	Get(ctx context.Context, userID int64) (*model.UserSettings, error)
+	GetByUsers(ctx context.Context, userIDs []int64) (map[int64]*model.UserSettings, error)
+	Save(ctx context.Context, settings *model.UserSettings) error
+}
+
+// SettingsRepository 用户设置仓储实现
+type SettingsRepository struct {
+	db *gorm.DB
+}
+
+// NewSettingsRepository 创建用户设置仓储
+func NewSettingsRepository(db *gorm.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Get 获取用户设置，未设置过时返回 nil
+func (r *SettingsRepository) Get(ctx context.Context, userID int64) (*model.UserSettings, error) {
+	var settings model.UserSettings
+	err := r.db.WithContext(ctx).First(&settings, "user_id = ?", userID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetByUsers 批量获取用户设置，按用户ID索引
+func (r *SettingsRepository) GetByUsers(ctx context.Context, userIDs []int64) (map[int64]*model.UserSettings, error) {
+	result := make(map[int64]*model.UserSettings, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var list []*model.UserSettings
+	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range list {
+		result[s.UserID] = s
+	}
+	return result, nil
+}
+
+// Save 保存用户设置
+func (r *SettingsRepository) Save(ctx context.Context, settings *model.UserSettings) error {
+	return r.db.WithContext(ctx).Save(settings).Error
+}
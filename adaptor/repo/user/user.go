@@ -13,6 +13,7 @@ import (
 type IUser interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*model.User, error)
 	GetByMobile(ctx context.Context, mobile string) (*model.User, error)
 	GetByOpenID(ctx context.Context, openID string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
@@ -41,6 +42,21 @@ func (u *User) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	return &user, err
 }
 
+func (u *User) GetByIDs(ctx context.Context, ids []int64) (map[int64]*model.User, error) {
+	result := make(map[int64]*model.User, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var users []*model.User
+	if err := u.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		result[user.ID] = user
+	}
+	return result, nil
+}
+
 func (u *User) GetByMobile(ctx context.Context, mobile string) (*model.User, error) {
 	var user model.User
 	err := u.db.WithContext(ctx).Where("mobile = ?", mobile).First(&user).Error
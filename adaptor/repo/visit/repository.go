@@ -0,0 +1,209 @@
+package visit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/adaptor/repo/model"
+)
+
+var errDuplicateVisit = errors.New("duplicate visit")
+
+// IVisitRepository 地点与停留仓储接口
+type IVisitRepository interface {
+	GetPlaces(ctx context.Context, userID int64) ([]*model.Place, error)
+	GetPlace(ctx context.Context, placeID int64) (*model.Place, error)
+	GetPlacesByIDs(ctx context.Context, placeIDs []int64) (map[int64]*model.Place, error)
+	UpdatePlaceName(ctx context.Context, placeID int64, name string) error
+	SaveVisit(ctx context.Context, place *model.Place, visit *model.Visit) (bool, error)
+	GetLastVisit(ctx context.Context, userID int64) (*model.Visit, error)
+	GetLastVisitBefore(ctx context.Context, userID int64, before time.Time) (*model.Visit, error)
+	RollbackVisits(ctx context.Context, userID int64, from time.Time) (int64, error)
+	GetVisits(ctx context.Context, userID int64, startTime, endTime time.Time, limit, offset int) ([]*model.Visit, error)
+}
+
+// VisitRepository 地点与停留仓储实现
+type VisitRepository struct {
+	db *gorm.DB
+}
+
+// NewVisitRepository 创建地点与停留仓储
+func NewVisitRepository(db *gorm.DB) *VisitRepository {
+	return &VisitRepository{db: db}
+}
+
+// GetPlaces 获取用户的所有地点
+func (r *VisitRepository) GetPlaces(ctx context.Context, userID int64) ([]*model.Place, error) {
+	var places []*model.Place
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("visit_count DESC").Find(&places).Error
+	return places, err
+}
+
+// GetPlace 获取地点，不存在时返回 nil
+func (r *VisitRepository) GetPlace(ctx context.Context, placeID int64) (*model.Place, error) {
+	var place model.Place
+	err := r.db.WithContext(ctx).First(&place, placeID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &place, nil
+}
+
+// GetPlacesByIDs 批量获取地点，按地点ID索引
+func (r *VisitRepository) GetPlacesByIDs(ctx context.Context, placeIDs []int64) (map[int64]*model.Place, error) {
+	result := make(map[int64]*model.Place, len(placeIDs))
+	if len(placeIDs) == 0 {
+		return result, nil
+	}
+	var places []*model.Place
+	if err := r.db.WithContext(ctx).Where("id IN ?", placeIDs).Find(&places).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range places {
+		result[p.ID] = p
+	}
+	return result, nil
+}
+
+// UpdatePlaceName 更新地点名称
+func (r *VisitRepository) UpdatePlaceName(ctx context.Context, placeID int64, name string) error {
+	return r.db.WithContext(ctx).Model(&model.Place{}).Where("id = ?", placeID).Update("name", name).Error
+}
+
+// SaveVisit 在同一事务中保存地点（新建或更新统计）和停留记录
+// 同一用户同一到达时间的停留已存在时整体回滚并返回 false，地点统计不会重复累加
+func (r *VisitRepository) SaveVisit(ctx context.Context, place *model.Place, visit *model.Visit) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(place).Error; err != nil {
+			return err
+		}
+		visit.PlaceID = place.ID
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(visit)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errDuplicateVisit
+		}
+		return nil
+	})
+	if err == errDuplicateVisit {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetLastVisit 获取用户最近一次停留，没有时返回 nil
+func (r *VisitRepository) GetLastVisit(ctx context.Context, userID int64) (*model.Visit, error) {
+	var visit model.Visit
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("departed_at DESC").First(&visit).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// GetLastVisitBefore 获取在 before 之前离开的最近一次停留，没有时返回 nil
+func (r *VisitRepository) GetLastVisitBefore(ctx context.Context, userID int64, before time.Time) (*model.Visit, error) {
+	var visit model.Visit
+	err := r.db.WithContext(ctx).Where("user_id = ? AND departed_at < ?", userID, before).Order("departed_at DESC").First(&visit).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// RollbackVisits 删除离开时间不早于 from 的停留，并在同一事务中扣除所属地点的统计
+// 地点中心按剩余停留重新平均；没有剩余停留的未命名地点一并删除，返回删除的停留数
+func (r *VisitRepository) RollbackVisits(ctx context.Context, userID int64, from time.Time) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var visits []*model.Visit
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND departed_at >= ?", userID, from).Find(&visits).Error; err != nil {
+			return err
+		}
+		if len(visits) == 0 {
+			return nil
+		}
+
+		visitIDs := make([]int64, len(visits))
+		byPlace := make(map[int64][]*model.Visit)
+		for i, v := range visits {
+			visitIDs[i] = v.ID
+			byPlace[v.PlaceID] = append(byPlace[v.PlaceID], v)
+		}
+		if err := tx.Where("id IN ?", visitIDs).Delete(&model.Visit{}).Error; err != nil {
+			return err
+		}
+
+		placeIDs := make([]int64, 0, len(byPlace))
+		for id := range byPlace {
+			placeIDs = append(placeIDs, id)
+		}
+		var places []*model.Place
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", placeIDs).Find(&places).Error; err != nil {
+			return err
+		}
+		for _, place := range places {
+			for _, v := range byPlace[place.ID] {
+				if n := float64(place.VisitCount); n > 1 {
+					place.Longitude = (place.Longitude*n - v.Longitude) / (n - 1)
+					place.Latitude = (place.Latitude*n - v.Latitude) / (n - 1)
+				}
+				place.VisitCount--
+				place.TotalDuration -= int64(v.Duration() / time.Second)
+			}
+
+			var last model.Visit
+			err := tx.Where("place_id = ?", place.ID).Order("departed_at DESC").First(&last).Error
+			switch {
+			case err == gorm.ErrRecordNotFound:
+				if place.Name == "" {
+					if err := tx.Delete(place).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				place.VisitCount, place.TotalDuration = 0, 0
+			case err != nil:
+				return err
+			default:
+				place.LastVisitAt = last.DepartedAt
+			}
+			if err := tx.Save(place).Error; err != nil {
+				return err
+			}
+		}
+		removed = int64(len(visits))
+		return nil
+	})
+	return removed, err
+}
+
+// GetVisits 获取时间段内的停留记录，按到达时间倒序
+func (r *VisitRepository) GetVisits(ctx context.Context, userID int64, startTime, endTime time.Time, limit, offset int) ([]*model.Visit, error) {
+	var visits []*model.Visit
+	query := r.db.WithContext(ctx).Where("user_id = ? AND departed_at >= ? AND arrived_at <= ?", userID, startTime, endTime)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	err := query.Order("arrived_at DESC").Find(&visits).Error
+	return visits, err
+}
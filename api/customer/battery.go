@@ -0,0 +1,97 @@
+package customer
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"app/api"
+	"app/common"
+	"app/service/dto"
+)
+
+// @Summary 获取电量告警记录
+// @Description 获取本人手机及名下设备的电量告警记录，按时间倒序
+// @Tags battery
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param entity_type query string false "实体类型 user/device"
+// @Param entity_id query string false "用户ID或设备ID"
+// @Param limit query int false "限制数量，默认50，最大500"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} api.Resp{data=[]dto.BatteryAlertResp}
+// @Router /api/app/customer/v1/battery/alerts [get]
+func (c *Ctrl) GetBatteryAlerts(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.BatteryAlertListReq{
+		EntityType: ctx.Query("entity_type"),
+		EntityID:   ctx.Query("entity_id"),
+		Limit:      parseInt(ctx.Query("limit")),
+		Offset:     parseInt(ctx.Query("offset")),
+	}
+
+	alerts, err := c.Battery.GetAlerts(ctx.Request.Context(), userID, req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, alerts, common.OK)
+}
+
+// @Summary 获取电量告警设置
+// @Description 获取本人手机的低电量告警阈值
+// @Tags battery
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Success 200 {object} api.Resp{data=dto.BatterySettingsResp}
+// @Router /api/app/customer/v1/battery/settings [get]
+func (c *Ctrl) GetBatterySettings(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	settings, err := c.Battery.GetSettings(ctx.Request.Context(), userID)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, settings, common.OK)
+}
+
+// @Summary 更新电量告警设置
+// @Description 更新本人手机的低电量告警阈值，设备的阈值通过设备设置更新
+// @Tags battery
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param req body dto.BatterySettingsReq true "告警设置"
+// @Success 200 {object} api.Resp{data=dto.BatterySettingsResp}
+// @Router /api/app/customer/v1/battery/settings [put]
+func (c *Ctrl) UpdateBatterySettings(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.BatterySettingsReq{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+		return
+	}
+
+	settings, err := c.Battery.UpdateSettings(ctx.Request.Context(), userID, req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, settings, common.OK)
+}
@@ -1,12 +1,19 @@
 package customer
 
 import (
+	"time"
+
 	"app/adaptor"
+	userRepo "app/adaptor/repo/user"
+	"app/service/battery"
 	"app/service/device"
 	"app/service/friend"
 	"app/service/geofence"
 	"app/service/location"
+	"app/service/presence"
+	"app/service/trip"
 	"app/service/user"
+	"app/service/visit"
 	"app/service/websocket"
 )
 
@@ -17,31 +24,63 @@ type Ctrl struct {
 	Friend   *friend.FriendService
 	Device   *device.DeviceService
 	Geofence *geofence.GeofenceService
+	Presence *presence.PresenceService
+	Visit    *visit.VisitService
+	Trip     *trip.TripService
+	Battery  *battery.BatteryService
 	Hub      *websocket.Hub
 }
 
 func NewCtrl(adaptor adaptor.IAdaptor) *Ctrl {
 	// 初始化Redis缓存
 	locationCache := adaptor.NewLocationCache()
+	geofenceCache := adaptor.NewGeofenceCache()
+	presenceCache := adaptor.NewPresenceCache()
+	batteryCache := adaptor.NewBatteryCache()
+	visitCache := adaptor.NewVisitCache()
 
 	// 初始化仓储
 	locationRepo := adaptor.NewLocationRepository()
 	friendRepo := adaptor.NewFriendRepository()
 	deviceRepo := adaptor.NewDeviceRepository()
 	geofenceRepo := adaptor.NewGeofenceRepository()
+	settingsRepo := adaptor.NewSettingsRepository()
+	visitRepo := adaptor.NewVisitRepository()
+	batteryRepo := adaptor.NewBatteryRepository()
 
-	// 初始化服务
-	locationSvc := location.NewLocationService(locationRepo, locationCache)
-	friendSvc := friend.NewFriendService(friendRepo)
-	deviceSvc := device.NewDeviceService(deviceRepo)
-	geofenceSvc := geofence.NewGeofenceService(geofenceRepo)
-
-	// 初始化WebSocket Hub
+	// 初始化WebSocket Hub，通过 Redis Pub/Sub 与其他实例互通
 	hub := websocket.NewHub()
+	hub.UseBroker(adaptor.NewPubSub())
 
 	// 启动Hub
 	go hub.Run()
 
+	// 初始化服务
+	geofenceSvc := geofence.NewGeofenceService(geofenceRepo, geofenceCache)
+	presenceSvc := presence.NewPresenceService(presenceCache, friendRepo, settingsRepo, hub)
+	go presenceSvc.Run()
+	batterySvc := battery.NewBatteryService(batteryRepo, batteryCache, settingsRepo, deviceRepo, hub)
+	deviceSvc := device.NewDeviceService(deviceRepo, hub, batterySvc, time.Duration(adaptor.GetConfig().Server.DeviceOfflineTimeout)*time.Second)
+	go deviceSvc.Run()
+	visitSvc := visit.NewVisitService(visitRepo, locationRepo, visitCache)
+	go visitSvc.Run()
+	locationSvc := location.NewLocationService(
+		locationRepo,
+		locationCache,
+		geofenceSvc,
+		friendRepo,
+		settingsRepo,
+		deviceRepo,
+		deviceSvc,
+		userRepo.NewUser(adaptor),
+		presenceSvc,
+		batterySvc,
+		visitSvc,
+		hub,
+	)
+	friendSvc := friend.NewFriendService(friendRepo, presenceSvc)
+	tripSvc := trip.NewTripService(locationRepo)
+
 	return &Ctrl{
 		Adaptor:  adaptor,
 		User:     user.NewService(adaptor),
@@ -49,6 +88,10 @@ func NewCtrl(adaptor adaptor.IAdaptor) *Ctrl {
 		Friend:   friendSvc,
 		Device:   deviceSvc,
 		Geofence: geofenceSvc,
+		Presence: presenceSvc,
+		Visit:    visitSvc,
+		Trip:     tripSvc,
+		Battery:  batterySvc,
 		Hub:      hub,
 	}
 }
@@ -20,13 +20,13 @@ func NewDeviceCtrl(device *device.DeviceService) *DeviceCtrl {
 }
 
 // @Summary 绑定设备
-// @Description 绑定追踪设备
+// @Description 绑定追踪设备，返回设备接入密钥（仅返回一次）
 // @Tags device
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Token"
 // @Param req body dto.DeviceBindReq true "设备信息"
-// @Success 200 {object} api.Resp
+// @Success 200 {object} api.Resp{data=dto.DeviceAPIKeyResp}
 // @Router /api/app/customer/v1/device/bind [post]
 func (c *Ctrl) BindDevice(ctx *gin.Context) {
 	req := &dto.DeviceBindReq{}
@@ -36,12 +36,34 @@ func (c *Ctrl) BindDevice(ctx *gin.Context) {
 	}
 
 	userID := getUserID(ctx)
-	if err := c.Device.BindDevice(ctx.Request.Context(), userID, req); err != nil {
+	resp, err := c.Device.BindDevice(ctx.Request.Context(), userID, req)
+	if err != nil {
 		api.WriteResp(ctx, nil, err.(common.Errno))
 		return
 	}
 
-	api.WriteResp(ctx, nil, common.OK)
+	api.WriteResp(ctx, resp, common.OK)
+}
+
+// @Summary 轮换设备接入密钥
+// @Description 重新生成设备接入密钥，旧密钥立即失效
+// @Tags device
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param device_id path string true "设备ID"
+// @Success 200 {object} api.Resp{data=dto.DeviceAPIKeyResp}
+// @Router /api/app/customer/v1/device/{device_id}/api-key [post]
+func (c *Ctrl) RotateDeviceAPIKey(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	deviceID := ctx.Param("device_id")
+
+	resp, err := c.Device.RotateAPIKey(ctx.Request.Context(), userID, deviceID)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, resp, common.OK)
 }
 
 // @Summary 解绑设备
@@ -122,6 +144,7 @@ func (c *Ctrl) UpdateDeviceSettings(ctx *gin.Context) {
 // @Success 200 {object} api.Resp
 // @Router /api/app/customer/v1/device/{device_id}/status [put]
 func (c *Ctrl) UpdateDeviceStatus(ctx *gin.Context) {
+	userID := getUserID(ctx)
 	deviceID := ctx.Param("device_id")
 
 	req := &dto.DeviceStatusReq{}
@@ -130,7 +153,93 @@ func (c *Ctrl) UpdateDeviceStatus(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.Device.UpdateDeviceStatus(ctx.Request.Context(), deviceID, req); err != nil {
+	if err := c.Device.UpdateDeviceStatus(ctx.Request.Context(), userID, deviceID, req); err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, nil, common.OK)
+}
+
+// @Summary 获取设备状态变化记录
+// @Description 获取设备在线/离线状态变化记录，按时间倒序
+// @Tags device
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param device_id path string true "设备ID"
+// @Param limit query int false "限制数量，默认50，最大500"
+// @Success 200 {object} api.Resp{data=[]dto.DeviceStatusEventResp}
+// @Router /api/app/customer/v1/device/{device_id}/status/history [get]
+func (c *Ctrl) GetDeviceStatusEvents(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	deviceID := ctx.Param("device_id")
+
+	events, err := c.Device.GetStatusEvents(ctx.Request.Context(), userID, deviceID, parseInt(ctx.Query("limit")))
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, events, common.OK)
+}
+
+// @Summary 设备上报位置
+// @Description 设备使用接入密钥上报自身位置，无需用户登录
+// @Tags device
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string true "设备ID"
+// @Param X-Device-Key header string true "设备接入密钥"
+// @Param req body dto.LocationReportReq true "位置信息"
+// @Success 200 {object} api.Resp
+// @Router /api/app/device/v1/location/report [post]
+func (c *Ctrl) DeviceReport(ctx *gin.Context) {
+	dev := api.GetDeviceFromCtx(ctx)
+	if dev == nil {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.LocationReportReq{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+		return
+	}
+
+	if err := c.Location.ReportAuthedDeviceLocation(ctx.Request.Context(), dev, req); err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, nil, common.OK)
+}
+
+// @Summary 设备心跳
+// @Description 设备使用接入密钥上报心跳，刷新在线状态
+// @Tags device
+// @Accept json
+// @Produce json
+// @Param X-Device-ID header string true "设备ID"
+// @Param X-Device-Key header string true "设备接入密钥"
+// @Param req body dto.DeviceHeartbeatReq false "心跳信息"
+// @Success 200 {object} api.Resp
+// @Router /api/app/device/v1/heartbeat [post]
+func (c *Ctrl) DeviceHeartbeat(ctx *gin.Context) {
+	dev := api.GetDeviceFromCtx(ctx)
+	if dev == nil {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.DeviceHeartbeatReq{}
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.BindJSON(req); err != nil {
+			api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+			return
+		}
+	}
+
+	if err := c.Device.Heartbeat(ctx.Request.Context(), dev, req); err != nil {
 		api.WriteResp(ctx, nil, err.(common.Errno))
 		return
 	}
@@ -1,14 +1,29 @@
 package customer
 
 import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 
 	"app/api"
 	"app/common"
 	"app/service/dto"
 	"app/service/location"
+	"app/utils/logger"
+)
+
+const (
+	// 导入文件的大小上限
+	maxImportFileSize = 10 << 20
+
+	// 批量上报的幂等键请求头及其最大长度，与请求体中 idempotency_key 的限制一致
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 64
 )
 
 // LocationCtrl 位置控制器 - 嵌入到 Ctrl 中
@@ -52,8 +67,9 @@ func (c *Ctrl) Report(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Token"
+// @Param Idempotency-Key header string false "批次幂等键，重试时保持不变"
 // @Param req body dto.BatchLocationReportReq true "位置列表"
-// @Success 200 {object} api.Resp
+// @Success 200 {object} api.Resp{data=dto.BatchLocationReportResp}
 // @Router /api/app/customer/v1/location/batch [post]
 func (c *Ctrl) BatchReport(ctx *gin.Context) {
 	req := &dto.BatchLocationReportReq{}
@@ -61,14 +77,22 @@ func (c *Ctrl) BatchReport(ctx *gin.Context) {
 		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
 		return
 	}
+	if key := ctx.GetHeader(idempotencyKeyHeader); key != "" {
+		if len(key) > maxIdempotencyKeyLength {
+			api.WriteResp(ctx, nil, common.ParamErr.WithMsg("idempotency key too long"))
+			return
+		}
+		req.IdempotencyKey = key
+	}
 
 	userID := getUserID(ctx)
-	if err := c.Location.BatchReportLocation(ctx.Request.Context(), userID, req); err != nil {
+	resp, err := c.Location.BatchReportLocation(ctx.Request.Context(), userID, req)
+	if err != nil {
 		api.WriteResp(ctx, nil, err.(common.Errno))
 		return
 	}
 
-	api.WriteResp(ctx, gin.H{"count": len(req.Locations)}, common.OK)
+	api.WriteResp(ctx, resp, common.OK)
 }
 
 // @Summary 获取用户位置
@@ -118,13 +142,16 @@ func (c *Ctrl) GetDeviceLocation(ctx *gin.Context) {
 // @Tags location
 // @Produce json
 // @Param Authorization header string true "Token"
-// @Param user_id query int true "用户ID"
+// @Param user_id query int false "用户ID，默认本人"
 // @Param start_time query string true "开始时间"
 // @Param end_time query string true "结束时间"
 // @Param limit query int false "限制数量"
+// @Param format query string false "输出格式：json（默认）、geojson、polyline"
+// @Param precision query int false "polyline 格式的坐标精度（小数位数），默认5"
 // @Success 200 {object} api.Resp{data=[]dto.LocationResp}
 // @Router /api/app/customer/v1/location/history [get]
 func (c *Ctrl) GetLocationHistory(ctx *gin.Context) {
+	requesterID := getUserID(ctx)
 	userID := parseInt64(ctx.Query("user_id"))
 	startTime := parseTime(ctx.Query("start_time"))
 	endTime := parseTime(ctx.Query("end_time"))
@@ -137,13 +164,140 @@ func (c *Ctrl) GetLocationHistory(ctx *gin.Context) {
 		Limit:     limit,
 	}
 
-	locs, err := c.Location.GetLocationHistory(ctx.Request.Context(), req)
+	locs, err := c.Location.GetLocationHistory(ctx.Request.Context(), requesterID, req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	data, err := location.FormatHistory(locs, ctx.Query("format"), parseInt(ctx.Query("precision")))
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, data, common.OK)
+}
+
+// @Summary 获取轨迹
+// @Description 获取简化后的用户轨迹，包含总距离与时长
+// @Tags location
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param user_id query int false "用户ID，默认本人"
+// @Param start_time query string true "开始时间"
+// @Param end_time query string true "结束时间"
+// @Param tolerance query number false "简化容差（米），默认10"
+// @Param interval query int false "降采样间隔（秒）"
+// @Param format query string false "输出格式：json（默认）、geojson、polyline"
+// @Param precision query int false "polyline 格式的坐标精度（小数位数），默认5"
+// @Success 200 {object} api.Resp{data=dto.TrajectoryResp}
+// @Router /api/app/customer/v1/location/trajectory [get]
+func (c *Ctrl) GetTrajectory(ctx *gin.Context) {
+	req := &dto.TrajectoryReq{
+		UserID:    parseInt64(ctx.Query("user_id")),
+		StartTime: parseTime(ctx.Query("start_time")),
+		EndTime:   parseTime(ctx.Query("end_time")),
+		Tolerance: parseFloat(ctx.Query("tolerance")),
+		Interval:  parseInt(ctx.Query("interval")),
+	}
+
+	trajectory, err := c.Location.GetTrajectory(ctx.Request.Context(), getUserID(ctx), req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	data, err := location.FormatTrajectory(trajectory, ctx.Query("format"), parseInt(ctx.Query("precision")))
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, data, common.OK)
+}
+
+// @Summary 导出轨迹
+// @Description 将本人时间段内的位置导出为 GPX 1.1 或 KML 文件
+// @Tags location
+// @Produce xml
+// @Param Authorization header string true "Token"
+// @Param start_time query string true "开始时间"
+// @Param end_time query string true "结束时间"
+// @Param format query string false "导出格式：gpx（默认）、kml"
+// @Success 200 {file} file
+// @Router /api/app/customer/v1/location/export [get]
+func (c *Ctrl) ExportTrack(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+	req := &dto.TrackExportReq{
+		StartTime: parseTime(ctx.Query("start_time")),
+		EndTime:   parseTime(ctx.Query("end_time")),
+		Format:    ctx.Query("format"),
+	}
+	if err := c.Location.CheckTrackExport(req); err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	ctx.Header("Content-Type", location.ExportContentType(req))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, location.ExportFilename(req)))
+	if err := c.Location.ExportTrack(ctx.Request.Context(), userID, req, ctx.Writer); err != nil {
+		// 已开始输出文件，无法再返回错误响应
+		logger.Warn("export track error", zap.Error(err), zap.Int64("user_id", userID))
+	}
+}
+
+// @Summary 导入轨迹
+// @Description 从 GPX 或 GeoJSON 文件导入本人的历史位置
+// @Tags location
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param file formData file true "GPX 或 GeoJSON 文件"
+// @Param format formData string false "文件格式：gpx、geojson，默认按扩展名判断"
+// @Success 200 {object} api.Resp{data=dto.LocationImportResp}
+// @Router /api/app/customer/v1/location/import [post]
+func (c *Ctrl) ImportTrack(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+		return
+	}
+	format := ctx.PostForm("format")
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+		case ".gpx":
+			format = dto.FormatGPX
+		case ".geojson", ".json":
+			format = dto.FormatGeoJSON
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+		return
+	}
+	defer file.Close()
+
+	resp, err := c.Location.ImportLocations(ctx.Request.Context(), userID, format, file)
 	if err != nil {
 		api.WriteResp(ctx, nil, err.(common.Errno))
 		return
 	}
 
-	api.WriteResp(ctx, locs, common.OK)
+	api.WriteResp(ctx, resp, common.OK)
 }
 
 // @Summary 获取附近好友
@@ -172,9 +326,9 @@ func (c *Ctrl) GetNearbyFriends(ctx *gin.Context) {
 
 // Helper functions
 func getUserID(ctx *gin.Context) int64 {
-	// 从JWT token中获取用户ID
-	if v, exists := ctx.Get("user_id"); exists {
-		return v.(int64)
+	// 从鉴权中间件写入的用户信息中获取用户ID
+	if user := api.GetUserFromCtx(ctx); user != nil {
+		return user.UserID
 	}
 	return 0
 }
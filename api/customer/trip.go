@@ -0,0 +1,39 @@
+package customer
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"app/api"
+	"app/common"
+	"app/service/dto"
+)
+
+// @Summary 获取行程
+// @Description 将本人时间段内的位置历史按静止期划分为行程，并推断移动方式
+// @Tags location
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param start_time query string true "开始时间"
+// @Param end_time query string true "结束时间"
+// @Success 200 {object} api.Resp{data=[]dto.TripResp}
+// @Router /api/app/customer/v1/location/trips [get]
+func (c *Ctrl) GetTrips(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.TripListReq{
+		StartTime: parseTime(ctx.Query("start_time")),
+		EndTime:   parseTime(ctx.Query("end_time")),
+	}
+
+	trips, err := c.Trip.GetTrips(ctx.Request.Context(), userID, req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, trips, common.OK)
+}
@@ -0,0 +1,94 @@
+package customer
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"app/api"
+	"app/common"
+	"app/service/dto"
+)
+
+// @Summary 获取停留记录
+// @Description 获取本人在各地点的停留记录，包含到达与离开时间
+// @Tags location
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param start_time query string false "开始时间，默认结束时间前30天"
+// @Param end_time query string false "结束时间，默认当前时间"
+// @Param limit query int false "限制数量"
+// @Param offset query int false "偏移量"
+// @Success 200 {object} api.Resp{data=[]dto.VisitResp}
+// @Router /api/app/customer/v1/location/visits [get]
+func (c *Ctrl) GetVisits(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	req := &dto.VisitListReq{
+		StartTime: parseTime(ctx.Query("start_time")),
+		EndTime:   parseTime(ctx.Query("end_time")),
+		Limit:     parseInt(ctx.Query("limit")),
+		Offset:    parseInt(ctx.Query("offset")),
+	}
+
+	visits, err := c.Visit.GetVisits(ctx.Request.Context(), userID, req)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, visits, common.OK)
+}
+
+// @Summary 获取常去地点
+// @Description 获取由停留聚类得到的常去地点
+// @Tags location
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Success 200 {object} api.Resp{data=[]dto.PlaceResp}
+// @Router /api/app/customer/v1/location/places [get]
+func (c *Ctrl) GetPlaces(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		api.WriteResp(ctx, nil, common.AuthErr)
+		return
+	}
+
+	places, err := c.Visit.GetPlaces(ctx.Request.Context(), userID)
+	if err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, places, common.OK)
+}
+
+// @Summary 命名地点
+// @Description 为常去地点设置名称
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Token"
+// @Param place_id path int true "地点ID"
+// @Param req body dto.PlaceUpdateReq true "地点名称"
+// @Success 200 {object} api.Resp
+// @Router /api/app/customer/v1/location/places/{place_id} [put]
+func (c *Ctrl) UpdatePlace(ctx *gin.Context) {
+	userID := getUserID(ctx)
+	placeID := parseInt64(ctx.Param("place_id"))
+
+	req := &dto.PlaceUpdateReq{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.WriteResp(ctx, nil, common.ParamErr.WithErr(err))
+		return
+	}
+
+	if err := c.Visit.UpdatePlace(ctx.Request.Context(), userID, placeID, req); err != nil {
+		api.WriteResp(ctx, nil, err.(common.Errno))
+		return
+	}
+
+	api.WriteResp(ctx, nil, common.OK)
+}
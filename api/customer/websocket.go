@@ -1,15 +1,18 @@
 package customer
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	gorillawebsocket "github.com/gorilla/websocket"
 
 	"app/api"
 	"app/common"
+	"app/service/dto"
 	ws "app/service/websocket"
 )
 
@@ -49,42 +52,117 @@ func (c *Ctrl) WebSocketConnect(ctx *gin.Context) {
 	)
 
 	c.Hub.Register(client)
+	stopPresence := c.Presence.Track(userID, client.ID)
 
 	go client.WritePump()
-	go client.ReadPump(handleWSMessage)
+	go func() {
+		client.ReadPump(func(msg *ws.Message) error {
+			return c.handleWSMessage(client, msg)
+		})
+		stopPresence()
+	}()
 }
 
-func handleWSMessage(msg *ws.Message) error {
+func (c *Ctrl) handleWSMessage(client *ws.Client, msg *ws.Message) error {
 	switch msg.Type {
 	case "subscribe":
-		// 处理订阅逻辑
-		var payload struct {
-			Entities []struct {
-				Type string `json:"type"`
-				ID   string `json:"id"`
-			} `json:"entities"`
-		}
+		var payload dto.WSSubscribeReq
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			replyWSError(client, msg, common.ParamErr.WithErr(err))
 			return err
 		}
-		// TODO: 实现订阅管理
+		replyWS(client, msg, ws.MessageTypeSubscribeAck, c.subscribe(client, payload.Entities))
 
 	case "unsubscribe":
-		// 处理取消订阅逻辑
-		var payload struct {
-			Entities []struct {
-				Type string `json:"type"`
-				ID   string `json:"id"`
-			} `json:"entities"`
-		}
+		var payload dto.WSSubscribeReq
 		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			replyWSError(client, msg, common.ParamErr.WithErr(err))
+			return err
+		}
+		ack := &dto.WSSubscribeAck{Accepted: []dto.WSEntity{}, Rejected: []dto.WSRejectedEntity{}}
+		for _, e := range payload.Entities {
+			client.Unsubscribe(e.Type, e.ID)
+			ack.Accepted = append(ack.Accepted, e)
+		}
+		replyWS(client, msg, ws.MessageTypeUnsubscribeAck, ack)
+
+	case "location_report":
+		req := &dto.LocationReportReq{}
+		if err := decodeWSPayload(msg, req); err != nil {
+			replyWSError(client, msg, err)
+			return err
+		}
+		if err := c.Location.ReportLocation(context.Background(), client.UserID, req); err != nil {
+			replyWSError(client, msg, err)
 			return err
 		}
-		// TODO: 实现取消订阅管理
+		replyWS(client, msg, ws.MessageTypeAck, &dto.WSReportAck{Count: 1})
+
+	case "location_batch_report":
+		req := &dto.BatchLocationReportReq{}
+		if err := decodeWSPayload(msg, req); err != nil {
+			replyWSError(client, msg, err)
+			return err
+		}
+		resp, err := c.Location.BatchReportLocation(context.Background(), client.UserID, req)
+		if err != nil {
+			replyWSError(client, msg, err)
+			return err
+		}
+		replyWS(client, msg, ws.MessageTypeAck, resp)
 
 	case "ping":
 		// 心跳处理
+		replyWS(client, msg, ws.MessageTypePong, nil)
+
+	default:
+		replyWSError(client, msg, common.ParamErr.WithMsg("unsupported message type"))
 	}
 
 	return nil
 }
+
+// decodeWSPayload 解析消息体并按 binding 标签校验，与 HTTP 接口的参数校验保持一致
+func decodeWSPayload(msg *ws.Message, req interface{}) error {
+	if err := json.Unmarshal(msg.Payload, req); err != nil {
+		return common.ParamErr.WithErr(err)
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return common.ParamErr.WithErr(err)
+	}
+	return nil
+}
+
+// replyWS 应答客户端请求，带回请求ID
+func replyWS(client *ws.Client, req *ws.Message, msgType string, payload interface{}) {
+	resp := ws.NewMessage(msgType, payload)
+	resp.ID = req.ID
+	client.SendMessage(resp)
+}
+
+// replyWSError 以 error 消息应答，非 Errno 错误按服务端错误处理
+func replyWSError(client *ws.Client, req *ws.Message, err error) {
+	errno, ok := err.(common.Errno)
+	if !ok {
+		errno = common.ServerErr.WithErr(err)
+	}
+	replyWS(client, req, ws.MessageTypeError, &dto.WSError{Code: errno.Code, Msg: errno.Msg})
+}
+
+// subscribe 校验位置可见性后订阅实体，无权查看的实体放入 rejected
+func (c *Ctrl) subscribe(client *ws.Client, entities []dto.WSEntity) *dto.WSSubscribeAck {
+	ack := &dto.WSSubscribeAck{Accepted: []dto.WSEntity{}, Rejected: []dto.WSRejectedEntity{}}
+	ctx := context.Background()
+	for _, e := range entities {
+		if err := c.Location.CheckEntityVisible(ctx, client.UserID, e.Type, e.ID); err != nil {
+			ack.Rejected = append(ack.Rejected, dto.WSRejectedEntity{Type: e.Type, ID: e.ID, Reason: err.Error()})
+			continue
+		}
+		if !client.Subscribe(e.Type, e.ID) {
+			ack.Rejected = append(ack.Rejected, dto.WSRejectedEntity{Type: e.Type, ID: e.ID, Reason: "Too Many Subscriptions"})
+			continue
+		}
+		ack.Accepted = append(ack.Accepted, e)
+	}
+	return ack
+}
@@ -53,3 +53,11 @@ func GetAdminUserFromCtx(ctx *gin.Context) *common.AdminUser {
 	}
 	return user.(*common.AdminUser)
 }
+
+func GetDeviceFromCtx(ctx *gin.Context) *common.Device {
+	device, exist := ctx.Get(consts.DeviceKey)
+	if !exist {
+		return nil
+	}
+	return device.(*common.Device)
+}
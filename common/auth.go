@@ -9,3 +9,9 @@ type User struct {
 	UserID   int64  `json:"user_id"`
 	NickName string `json:"nick_name"`
 }
+
+// Device 通过设备密钥鉴权的设备
+type Device struct {
+	DeviceID string `json:"device_id"`
+	UserID   int64  `json:"user_id"`
+}
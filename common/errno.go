@@ -45,6 +45,8 @@ var (
 	LocationNotFoundErr   = Errno{Code: 12001, Msg: "Location Not Found"}
 	LocationExpiredErr    = Errno{Code: 12002, Msg: "Location Data Expired"}
 	InvalidCoordinatesErr = Errno{Code: 12003, Msg: "Invalid Coordinates"}
+	LocationRejectedErr   = Errno{Code: 12004, Msg: "Location Rejected"}
+	PlaceNotFoundErr      = Errno{Code: 12005, Msg: "Place Not Found"}
 
 	// 好友相关错误 (13000-13999)
 	FriendNotFoundErr      = Errno{Code: 13001, Msg: "Friend Not Found"}
@@ -43,6 +43,9 @@ type Server struct {
 	EnablePprof     bool   `yaml:"enable_pprof"`
 	LogLevel        string `yaml:"log_level"`
 	ShutdownTimeout int    `yaml:"shutdown_timeout"` // 优雅关闭超时时间(秒)
+
+	DeviceOfflineTimeout int `yaml:"device_offline_timeout"` // 设备无上报或心跳多久后置为离线(秒)
+	TrackerPort          int `yaml:"tracker_port"`           // GT06 协议追踪器接入端口，0 表示不启动
 }
 
 type Mysql struct {
@@ -126,6 +129,11 @@ func applyEnvOverrides(conf *Config) {
 			conf.Server.HttpPort = port
 		}
 	}
+	if v := os.Getenv("APP_TRACKER_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			conf.Server.TrackerPort = port
+		}
+	}
 	if v := os.Getenv("APP_ENV"); v != "" {
 		conf.Server.Env = v
 	}
@@ -164,6 +172,9 @@ func applyEnvOverrides(conf *Config) {
 	if conf.Server.ShutdownTimeout == 0 {
 		conf.Server.ShutdownTimeout = 10
 	}
+	if conf.Server.DeviceOfflineTimeout == 0 {
+		conf.Server.DeviceOfflineTimeout = 300
+	}
 }
 
 func getFromRemoteAndWatchUpdate(v *viper.Viper) (*Config, error) {
@@ -7,6 +7,13 @@ const (
 	AdminUserKey    = "admin_user_key"
 )
 
+// 设备接入鉴权
+const (
+	DeviceIDHeader  = "X-Device-ID"
+	DeviceKeyHeader = "X-Device-Key"
+	DeviceKey       = "device_key"
+)
+
 const (
 	IsEnable  = 1
 	IsDisable = -1
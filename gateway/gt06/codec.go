@@ -0,0 +1,212 @@
+package gt06
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 协议号
+const (
+	ProtocolLogin       = 0x01
+	ProtocolLocation    = 0x12
+	ProtocolHeartbeat   = 0x13
+	ProtocolAlarm       = 0x16
+	ProtocolLocationExt = 0x22 // GT06N 位置数据，GPS 部分与 0x12 相同
+)
+
+var (
+	startShort = [2]byte{0x78, 0x78} // 1 字节包长度
+	startLong  = [2]byte{0x79, 0x79} // 2 字节包长度
+	stopBits   = [2]byte{0x0D, 0x0A}
+)
+
+const (
+	// 协议号 + 序列号 + 校验位，信息内容之外的长度
+	packetOverhead = 1 + 2 + 2
+	// 信息内容的最大长度，超过时视为非法数据
+	maxContentLength = 1024
+
+	// GPS 信息：日期时间 6 + 卫星数 1 + 纬度 4 + 经度 4 + 速度 1 + 航向状态 2
+	gpsLength = 18
+	// 经纬度单位：分 × 30000
+	coordinateScale = 30000.0 * 60
+)
+
+var (
+	ErrBadStart  = errors.New("gt06: bad start bits")
+	ErrBadStop   = errors.New("gt06: bad stop bits")
+	ErrBadLength = errors.New("gt06: bad packet length")
+	ErrBadCRC    = errors.New("gt06: crc mismatch")
+)
+
+// Packet 一个完整的数据包
+type Packet struct {
+	Protocol byte
+	Content  []byte // 信息内容
+	Serial   uint16 // 信息序列号，应答时原样带回
+}
+
+// ReadPacket 从连接读取一个数据包
+func ReadPacket(r *bufio.Reader) (*Packet, error) {
+	var start [2]byte
+	if _, err := io.ReadFull(r, start[:]); err != nil {
+		return nil, err
+	}
+
+	var header []byte
+	var length int
+	switch start {
+	case startShort:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		header, length = []byte{b}, int(b)
+	case startLong:
+		header = make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		length = int(binary.BigEndian.Uint16(header))
+	default:
+		return nil, ErrBadStart
+	}
+	if length < packetOverhead || length > packetOverhead+maxContentLength {
+		return nil, ErrBadLength
+	}
+
+	body := make([]byte, length+len(stopBits))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	if body[length] != stopBits[0] || body[length+1] != stopBits[1] {
+		return nil, ErrBadStop
+	}
+
+	// 校验范围为包长度到序列号
+	crc := binary.BigEndian.Uint16(body[length-2:])
+	if crcITU(append(header, body[:length-2]...)) != crc {
+		return nil, ErrBadCRC
+	}
+
+	return &Packet{
+		Protocol: body[0],
+		Content:  body[1 : length-4],
+		Serial:   binary.BigEndian.Uint16(body[length-4:]),
+	}, nil
+}
+
+// Ack 生成对数据包的应答：协议号与序列号同请求，不带信息内容
+func Ack(p *Packet) []byte {
+	buf := make([]byte, 0, 2+1+packetOverhead+2)
+	buf = append(buf, startShort[:]...)
+	buf = append(buf, packetOverhead, p.Protocol)
+	buf = binary.BigEndian.AppendUint16(buf, p.Serial)
+	buf = binary.BigEndian.AppendUint16(buf, crcITU(buf[2:]))
+	return append(buf, stopBits[:]...)
+}
+
+// ParseIMEI 解析登录包中的终端ID（8 字节 BCD），去掉补齐用的首位 0
+func ParseIMEI(content []byte) (string, error) {
+	if len(content) < 8 {
+		return "", fmt.Errorf("gt06: login content too short: %d", len(content))
+	}
+	var sb strings.Builder
+	for _, b := range content[:8] {
+		hi, lo := b>>4, b&0x0F
+		if hi > 9 || lo > 9 {
+			return "", fmt.Errorf("gt06: invalid bcd terminal id % X", content[:8])
+		}
+		sb.WriteByte('0' + hi)
+		sb.WriteByte('0' + lo)
+	}
+	return strings.TrimPrefix(sb.String(), "0"), nil
+}
+
+// Fix GPS 定位信息
+type Fix struct {
+	Time       time.Time // 定位时间（UTC）
+	Satellites int
+	Latitude   float64
+	Longitude  float64
+	Speed      float64 // 千米/小时
+	Course     float64 // 航向，正北为 0
+	Positioned bool    // 是否已定位
+}
+
+// ParseFix 解析位置、报警数据包开头的 GPS 信息
+func ParseFix(content []byte) (*Fix, error) {
+	if len(content) < gpsLength {
+		return nil, fmt.Errorf("gt06: gps content too short: %d", len(content))
+	}
+	status := binary.BigEndian.Uint16(content[16:18])
+	fix := &Fix{
+		Time: time.Date(2000+int(content[0]), time.Month(content[1]), int(content[2]),
+			int(content[3]), int(content[4]), int(content[5]), 0, time.UTC),
+		Satellites: int(content[6] & 0x0F),
+		Latitude:   float64(binary.BigEndian.Uint32(content[7:11])) / coordinateScale,
+		Longitude:  float64(binary.BigEndian.Uint32(content[11:15])) / coordinateScale,
+		Speed:      float64(content[15]),
+		Course:     float64(status & 0x03FF),
+		Positioned: status&0x1000 != 0,
+	}
+	// bit10 为 1 表示北纬，bit11 为 1 表示西经
+	if status&0x0400 == 0 {
+		fix.Latitude = -fix.Latitude
+	}
+	if status&0x0800 != 0 {
+		fix.Longitude = -fix.Longitude
+	}
+	return fix, nil
+}
+
+// Status 心跳包中的终端状态
+type Status struct {
+	Charging     bool
+	VoltageLevel int // 电压等级 0-6
+	GSMSignal    int // 信号强度 0-4
+}
+
+// ParseStatus 解析心跳包信息内容
+func ParseStatus(content []byte) (*Status, error) {
+	if len(content) < 3 {
+		return nil, fmt.Errorf("gt06: heartbeat content too short: %d", len(content))
+	}
+	return &Status{
+		Charging:     content[0]&0x04 != 0,
+		VoltageLevel: int(content[1]),
+		GSMSignal:    int(content[2]),
+	}, nil
+}
+
+// voltageBattery 电压等级对应的电量百分比
+var voltageBattery = [...]int{0, 5, 10, 25, 50, 75, 100}
+
+// BatteryLevel 按电压等级估算电量百分比，无法识别时返回 0
+func (s *Status) BatteryLevel() int {
+	if s.VoltageLevel < 0 || s.VoltageLevel >= len(voltageBattery) {
+		return 0
+	}
+	return voltageBattery[s.VoltageLevel]
+}
+
+// crcITU CRC-ITU（X.25）校验
+func crcITU(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
@@ -0,0 +1,324 @@
+package gt06
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// 协议文档中的示例数据包
+const (
+	loginFrame     = "78780D01012345678901234500018CDD0D0A"
+	loginAckFrame  = "787805010001D9DC0D0A"
+	heartbeatFrame = "78780A134004040001000FDCEE0D0A"
+	locationFrame  = "78781F120B081D112E10CF027AC7EB0C46584900148F01CC00287D001FB8000380810D0A"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// frame 组装数据包，long 为 true 时使用 0x7979 起始位和 2 字节包长度
+func frame(long bool, protocol byte, content []byte, serial uint16) []byte {
+	length := len(content) + packetOverhead
+	var buf []byte
+	if long {
+		buf = append(buf, startLong[:]...)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	} else {
+		buf = append(buf, startShort[:]...)
+		buf = append(buf, byte(length))
+	}
+	buf = append(buf, protocol)
+	buf = append(buf, content...)
+	buf = binary.BigEndian.AppendUint16(buf, serial)
+	buf = binary.BigEndian.AppendUint16(buf, crcITU(buf[2:]))
+	return append(buf, stopBits[:]...)
+}
+
+// gpsContent 编码 GPS 信息，lat/lon 为带符号的度数
+func gpsContent(at time.Time, lat, lon float64, course int, positioned bool) []byte {
+	at = at.UTC()
+	buf := []byte{
+		byte(at.Year() - 2000), byte(at.Month()), byte(at.Day()),
+		byte(at.Hour()), byte(at.Minute()), byte(at.Second()),
+		0xC9,
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(math.Round(math.Abs(lat)*coordinateScale)))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(math.Round(math.Abs(lon)*coordinateScale)))
+	buf = append(buf, 36)
+	status := uint16(course) & 0x03FF
+	if lat >= 0 {
+		status |= 0x0400
+	}
+	if lon < 0 {
+		status |= 0x0800
+	}
+	if positioned {
+		status |= 0x1000
+	}
+	return binary.BigEndian.AppendUint16(buf, status)
+}
+
+func TestCRCITU(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want uint16
+	}{
+		{"check value", []byte("123456789"), 0x906E},
+		{"login", mustHex(t, loginFrame)[2:14], 0x8CDD},
+		{"login ack", mustHex(t, loginAckFrame)[2:6], 0xD9DC},
+		{"heartbeat", mustHex(t, heartbeatFrame)[2:11], 0xDCEE},
+		{"location", mustHex(t, locationFrame)[2:32], 0x8081},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := crcITU(tt.data); got != tt.want {
+				t.Fatalf("crc = %04X, want %04X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	alarm := append(gpsContent(time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC), 31.2304, 121.4737, 90, true),
+		0x09, 0x01, 0xCC, 0x00, 0x28, 0x7D, 0x00, 0x1F, 0xB8, 0x40, 0x04, 0x04, 0x01, 0x00)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		protocol byte
+		serial   uint16
+		content  int
+	}{
+		{"login", mustHex(t, loginFrame), ProtocolLogin, 0x0001, 8},
+		{"login long", frame(true, ProtocolLogin, mustHex(t, "0123456789012345"), 0x0002), ProtocolLogin, 0x0002, 8},
+		{"heartbeat", mustHex(t, heartbeatFrame), ProtocolHeartbeat, 0x000F, 5},
+		{"heartbeat long", frame(true, ProtocolHeartbeat, mustHex(t, "4004040001"), 0x0010), ProtocolHeartbeat, 0x0010, 5},
+		{"location", mustHex(t, locationFrame), ProtocolLocation, 0x0003, 26},
+		{"location ext long", frame(true, ProtocolLocationExt, mustHex(t, locationFrame)[4:30], 0x0004), ProtocolLocationExt, 0x0004, 26},
+		{"alarm", frame(false, ProtocolAlarm, alarm, 0x0005), ProtocolAlarm, 0x0005, len(alarm)},
+		{"alarm long", frame(true, ProtocolAlarm, alarm, 0x0006), ProtocolAlarm, 0x0006, len(alarm)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ReadPacket(bufio.NewReader(bytes.NewReader(tt.data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Protocol != tt.protocol || p.Serial != tt.serial || len(p.Content) != tt.content {
+				t.Fatalf("got protocol %#x serial %d content %d bytes", p.Protocol, p.Serial, len(p.Content))
+			}
+		})
+	}
+}
+
+func TestReadPacketStream(t *testing.T) {
+	var stream []byte
+	for _, f := range []string{loginFrame, heartbeatFrame, locationFrame} {
+		stream = append(stream, mustHex(t, f)...)
+	}
+	stream = append(stream, frame(true, ProtocolHeartbeat, mustHex(t, "4004040001"), 0x0010)...)
+	want := []byte{ProtocolLogin, ProtocolHeartbeat, ProtocolLocation, ProtocolHeartbeat}
+
+	readers := map[string]func() io.Reader{
+		// 多个数据包在一次 TCP 读取中到达
+		"concatenated": func() io.Reader { return bytes.NewReader(stream) },
+		// 数据包被拆分到多次读取中
+		"one byte per read": func() io.Reader { return iotest.OneByteReader(bytes.NewReader(stream)) },
+		"half read":         func() io.Reader { return iotest.HalfReader(bytes.NewReader(stream)) },
+	}
+	for name, newReader := range readers {
+		t.Run(name, func(t *testing.T) {
+			r := bufio.NewReader(newReader())
+			for i, protocol := range want {
+				p, err := ReadPacket(r)
+				if err != nil {
+					t.Fatalf("packet %d: %v", i, err)
+				}
+				if p.Protocol != protocol {
+					t.Fatalf("packet %d: protocol %#x, want %#x", i, p.Protocol, protocol)
+				}
+			}
+			if _, err := ReadPacket(r); err != io.EOF {
+				t.Fatalf("after last packet: err = %v, want EOF", err)
+			}
+		})
+	}
+}
+
+func TestReadPacketInvalid(t *testing.T) {
+	login := mustHex(t, loginFrame)
+	corrupt := func(i int, b byte) []byte {
+		data := append([]byte(nil), login...)
+		data[i] = b
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"bad start", corrupt(1, 0x79), ErrBadStart},
+		{"bad stop", corrupt(len(login)-1, 0x0B), ErrBadStop},
+		{"bad crc", corrupt(len(login)-3, 0x00), ErrBadCRC},
+		{"corrupted content", corrupt(5, 0x99), ErrBadCRC},
+		{"length below overhead", mustHex(t, "7878040100010D0A"), ErrBadLength},
+		{"long length too large", mustHex(t, "7979FFFF01"), ErrBadLength},
+		{"truncated body", login[:10], io.ErrUnexpectedEOF},
+		{"truncated long length", mustHex(t, "797900"), io.ErrUnexpectedEOF},
+		{"truncated start", login[:1], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadPacket(bufio.NewReader(bytes.NewReader(tt.data)))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAck(t *testing.T) {
+	p, err := ReadPacket(bufio.NewReader(bytes.NewReader(mustHex(t, loginFrame))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(Ack(p)); got != hex.EncodeToString(mustHex(t, loginAckFrame)) {
+		t.Fatalf("ack = %s, want %s", got, loginAckFrame)
+	}
+
+	// 0x7979 数据包同样以 0x7878 短包应答，可被 ReadPacket 解析
+	long := &Packet{Protocol: ProtocolAlarm, Serial: 0x1234}
+	ack, err := ReadPacket(bufio.NewReader(bytes.NewReader(Ack(long))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Protocol != ProtocolAlarm || ack.Serial != 0x1234 || len(ack.Content) != 0 {
+		t.Fatalf("ack = %+v", ack)
+	}
+}
+
+func TestParseIMEI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"padded", "0123456789012345", "123456789012345", false},
+		{"sixteen digits", "8612345678901234", "8612345678901234", false},
+		{"trailing data ignored", "0358899051234567AA", "358899051234567", false},
+		{"invalid bcd", "01234567890123A5", "", true},
+		{"too short", "01234567890123", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIMEI(mustHex(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("imei = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFix(t *testing.T) {
+	content := mustHex(t, locationFrame)[4:30]
+	fix, err := ParseFix(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2011, 8, 29, 17, 46, 16, 0, time.UTC); !fix.Time.Equal(want) {
+		t.Fatalf("time = %v, want %v", fix.Time, want)
+	}
+	if fix.Satellites != 15 || fix.Course != 143 || fix.Speed != 0 || !fix.Positioned {
+		t.Fatalf("fix = %+v", fix)
+	}
+	if math.Abs(fix.Latitude-23.111664) > 1e-5 || math.Abs(fix.Longitude-114.409285) > 1e-5 {
+		t.Fatalf("position = %v,%v", fix.Latitude, fix.Longitude)
+	}
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		lat, lon   float64
+		positioned bool
+	}{
+		{"north east", 39.908722, 116.397472, true},
+		{"south west", -34.603722, -58.381592, true},
+		{"north west", 37.774929, -122.419418, true},
+		{"not positioned", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fix, err := ParseFix(gpsContent(at, tt.lat, tt.lon, 270, tt.positioned))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fix.Time.Equal(at) || fix.Course != 270 || fix.Speed != 36 || fix.Positioned != tt.positioned {
+				t.Fatalf("fix = %+v", fix)
+			}
+			if math.Abs(fix.Latitude-tt.lat) > 1e-6 || math.Abs(fix.Longitude-tt.lon) > 1e-6 {
+				t.Fatalf("position = %v,%v, want %v,%v", fix.Latitude, fix.Longitude, tt.lat, tt.lon)
+			}
+		})
+	}
+
+	if _, err := ParseFix(content[:gpsLength-1]); err == nil {
+		t.Fatal("expected error for short content")
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	p, err := ReadPacket(bufio.NewReader(bytes.NewReader(mustHex(t, heartbeatFrame))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := ParseStatus(p.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Charging || status.VoltageLevel != 4 || status.GSMSignal != 4 || status.BatteryLevel() != 50 {
+		t.Fatalf("status = %+v, battery %d", status, status.BatteryLevel())
+	}
+
+	tests := []struct {
+		content  string
+		charging bool
+		battery  int
+	}{
+		{"440601", true, 100},
+		{"000001", false, 0},
+		{"040904", true, 0},
+	}
+	for _, tt := range tests {
+		status, err := ParseStatus(mustHex(t, tt.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Charging != tt.charging || status.BatteryLevel() != tt.battery {
+			t.Fatalf("%s: status = %+v, battery %d", tt.content, status, status.BatteryLevel())
+		}
+	}
+
+	if _, err := ParseStatus([]byte{0x40, 0x04}); err == nil {
+		t.Fatal("expected error for short content")
+	}
+}
@@ -0,0 +1,266 @@
+package gt06
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"app/common"
+	"app/service/device"
+	"app/service/dto"
+	"app/service/location"
+	"app/utils/logger"
+)
+
+const (
+	// 终端默认每 3 分钟发送一次心跳，超过该时长没有数据时断开连接
+	idleTimeout  = 6 * time.Minute
+	writeTimeout = 10 * time.Second
+	// 登录包必须在连接建立后尽快发送
+	loginTimeout = 30 * time.Second
+)
+
+// Server GT06 协议追踪器接入服务
+//
+// 终端以登录包中的 IMEI 作为设备ID，必须先通过 /v1/device/bind 绑定。
+// 登录与心跳视同设备心跳，位置与报警包中已定位的 GPS 信息按设备位置上报处理。
+type Server struct {
+	addr     string
+	devices  device.IDeviceService
+	location location.ILocationService
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer 创建 GT06 接入服务
+func NewServer(port int, devices device.IDeviceService, location location.ILocationService) *Server {
+	return &Server{
+		addr:     fmt.Sprintf(":%d", port),
+		devices:  devices,
+		location: location,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// Start 监听端口并开始接受连接，监听失败时返回错误
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	logger.Info(fmt.Sprintf("gt06 gateway started, listen: %s", s.addr))
+	go s.serve(ln)
+	return nil
+}
+
+func (s *Server) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Warn("gt06 accept error", zap.Error(err))
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Shutdown 停止接受连接并断开所有终端，等待处理中的数据包完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// session 一个终端连接
+type session struct {
+	conn    net.Conn
+	imei    string
+	battery int // 最近一次心跳估算的电量，位置包不含电量
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	sess := &session{conn: conn}
+	r := bufio.NewReader(conn)
+	for {
+		timeout := idleTimeout
+		if sess.imei == "" {
+			timeout = loginTimeout
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		p, err := ReadPacket(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Debug("gt06 read error", zap.Error(err), zap.String("imei", sess.imei), zap.String("remote", conn.RemoteAddr().String()))
+			}
+			return
+		}
+		if !s.dispatch(sess, p) {
+			return
+		}
+	}
+}
+
+// dispatch 处理一个数据包，返回 false 时断开连接
+func (s *Server) dispatch(sess *session, p *Packet) bool {
+	ctx := context.Background()
+
+	if p.Protocol == ProtocolLogin {
+		return s.login(ctx, sess, p)
+	}
+	// 未登录的终端不处理其他数据包
+	if sess.imei == "" {
+		logger.Debug("gt06 packet before login", zap.Uint8("protocol", p.Protocol), zap.String("remote", sess.conn.RemoteAddr().String()))
+		return false
+	}
+
+	switch p.Protocol {
+	case ProtocolHeartbeat:
+		status, err := ParseStatus(p.Content)
+		if err != nil {
+			logger.Debug("gt06 bad heartbeat", zap.Error(err), zap.String("imei", sess.imei))
+			return false
+		}
+		req := &dto.DeviceHeartbeatReq{}
+		if battery := status.BatteryLevel(); battery > 0 {
+			sess.battery = battery
+			req.BatteryLevel = &battery
+		}
+		s.heartbeat(ctx, sess, req)
+		return s.ack(sess, p)
+	case ProtocolLocation, ProtocolLocationExt:
+		s.report(ctx, sess, p)
+		return true
+	case ProtocolAlarm:
+		s.report(ctx, sess, p)
+		return s.ack(sess, p)
+	default:
+		// 不支持的协议号（LBS、指令应答等）直接忽略
+		logger.Debug("gt06 unsupported protocol", zap.Uint8("protocol", p.Protocol), zap.String("imei", sess.imei))
+		return true
+	}
+}
+
+// login 校验终端 IMEI 已绑定，未绑定时不应答并断开
+func (s *Server) login(ctx context.Context, sess *session, p *Packet) bool {
+	imei, err := ParseIMEI(p.Content)
+	if err != nil {
+		logger.Debug("gt06 bad login", zap.Error(err), zap.String("remote", sess.conn.RemoteAddr().String()))
+		return false
+	}
+	if _, err := s.devices.Resolve(ctx, imei); err != nil {
+		logger.Info("gt06 login rejected", zap.String("imei", imei), zap.Error(err))
+		return false
+	}
+
+	sess.imei = imei
+	s.heartbeat(ctx, sess, &dto.DeviceHeartbeatReq{})
+	return s.ack(sess, p)
+}
+
+func (s *Server) heartbeat(ctx context.Context, sess *session, req *dto.DeviceHeartbeatReq) {
+	dev := &common.Device{DeviceID: sess.imei}
+	if err := s.devices.Heartbeat(ctx, dev, req); err != nil {
+		logger.Warn("gt06 heartbeat error", zap.Error(err), zap.String("imei", sess.imei))
+	}
+}
+
+// report 将已定位的 GPS 信息按设备位置上报，定位时间取 GPS 时间，未定位的点只刷新在线状态
+// 离线补传的点同样上报，由位置服务写入历史而不作为当前位置；
+// 每次上报重新确认绑定关系，终端在线期间被解绑或换绑时立即生效
+func (s *Server) report(ctx context.Context, sess *session, p *Packet) {
+	fix, err := ParseFix(p.Content)
+	if err != nil {
+		logger.Debug("gt06 bad location", zap.Error(err), zap.String("imei", sess.imei))
+		return
+	}
+	if !fix.Positioned {
+		s.heartbeat(ctx, sess, &dto.DeviceHeartbeatReq{})
+		return
+	}
+
+	dev, err := s.devices.Resolve(ctx, sess.imei)
+	if err != nil {
+		logger.Info("gt06 device unavailable", zap.String("imei", sess.imei), zap.Error(err))
+		return
+	}
+	req := &dto.LocationReportReq{
+		Longitude:    fix.Longitude,
+		Latitude:     fix.Latitude,
+		Speed:        fix.Speed / 3.6,
+		Bearing:      fix.Course,
+		BatteryLevel: sess.battery,
+		RecordedAt:   fix.Time,
+		DeviceID:     dev.DeviceID,
+	}
+	if err := s.location.ReportAuthedDeviceLocation(ctx, dev, req); err != nil {
+		logger.Debug("gt06 report location error", zap.Error(err), zap.String("imei", sess.imei))
+	}
+}
+
+func (s *Server) ack(sess *session, p *Packet) bool {
+	sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := sess.conn.Write(Ack(p)); err != nil {
+		logger.Debug("gt06 write error", zap.Error(err), zap.String("imei", sess.imei))
+		return false
+	}
+	return true
+}
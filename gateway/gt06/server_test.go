@@ -0,0 +1,215 @@
+package gt06
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"app/common"
+	"app/service/device"
+	"app/service/dto"
+	"app/service/location"
+)
+
+const testIMEI = "123456789012345"
+
+type heartbeatCall struct {
+	deviceID string
+	battery  *int
+}
+
+type fakeDevices struct {
+	device.IDeviceService
+
+	mu         sync.Mutex
+	bound      map[string]int64
+	heartbeats []heartbeatCall
+}
+
+func (d *fakeDevices) Resolve(ctx context.Context, deviceID string) (*common.Device, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	userID, ok := d.bound[deviceID]
+	if !ok {
+		return nil, common.DeviceNotFoundErr
+	}
+	return &common.Device{DeviceID: deviceID, UserID: userID}, nil
+}
+
+func (d *fakeDevices) Heartbeat(ctx context.Context, dev *common.Device, req *dto.DeviceHeartbeatReq) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.heartbeats = append(d.heartbeats, heartbeatCall{deviceID: dev.DeviceID, battery: req.BatteryLevel})
+	return nil
+}
+
+type fakeLocations struct {
+	location.ILocationService
+
+	mu      sync.Mutex
+	reports []*dto.LocationReportReq
+}
+
+func (l *fakeLocations) ReportAuthedDeviceLocation(ctx context.Context, dev *common.Device, req *dto.LocationReportReq) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reports = append(l.reports, req)
+	return nil
+}
+
+// startSession 通过 net.Pipe 建立一个终端连接，返回终端端连接和连接处理结束的通知
+func startSession(t *testing.T, s *Server) (net.Conn, *bufio.Reader, <-chan struct{}) {
+	t.Helper()
+	client, conn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.handle(conn)
+	}()
+	t.Cleanup(func() {
+		client.Close()
+		<-done
+	})
+	return client, bufio.NewReader(client), done
+}
+
+func newTestServer() (*Server, *fakeDevices, *fakeLocations) {
+	devices := &fakeDevices{bound: map[string]int64{testIMEI: 7}}
+	locations := &fakeLocations{}
+	return NewServer(0, devices, locations), devices, locations
+}
+
+func send(t *testing.T, conn net.Conn, data []byte) {
+	t.Helper()
+	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func expectAck(t *testing.T, conn net.Conn, r *bufio.Reader, protocol byte, serial uint16) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	p, err := ReadPacket(r)
+	if err != nil {
+		t.Fatalf("read ack: %v", err)
+	}
+	if p.Protocol != protocol || p.Serial != serial || len(p.Content) != 0 {
+		t.Fatalf("ack = %+v, want protocol %#x serial %d", p, protocol, serial)
+	}
+}
+
+func expectClosed(t *testing.T, conn net.Conn, r *bufio.Reader, done <-chan struct{}) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Fatalf("read after reject: err = %v, want EOF", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection handler did not exit")
+	}
+}
+
+func TestServerSession(t *testing.T) {
+	s, devices, locations := newTestServer()
+	conn, r, _ := startSession(t, s)
+
+	send(t, conn, mustHex(t, loginFrame))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	ack := make([]byte, len(loginAckFrame)/2)
+	if _, err := io.ReadFull(r, ack); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ack, mustHex(t, loginAckFrame)) {
+		t.Fatalf("login ack = %X, want %s", ack, loginAckFrame)
+	}
+
+	send(t, conn, mustHex(t, heartbeatFrame))
+	expectAck(t, conn, r, ProtocolHeartbeat, 0x000F)
+
+	// 位置包不应答
+	fixAt := time.Now().UTC().Truncate(time.Second).Add(-10 * time.Second)
+	send(t, conn, frame(false, ProtocolLocation, gpsContent(fixAt, 31.2304, 121.4737, 90, true), 0x0003))
+	// 未定位的点只刷新在线状态
+	send(t, conn, frame(false, ProtocolLocation, gpsContent(fixAt, 0, 0, 0, false), 0x0004))
+	// 离线补传的点按 GPS 时间上报
+	staleAt := fixAt.Add(-2 * time.Hour)
+	send(t, conn, frame(false, ProtocolLocation, gpsContent(staleAt, 31.2, 121.4, 45, true), 0x0007))
+	// 不支持的协议号忽略
+	send(t, conn, frame(false, 0x8A, nil, 0x0005))
+
+	alarm := append(gpsContent(fixAt, -33.8688, 151.2093, 180, true), 0x09, 0x01, 0xCC, 0x00, 0x28, 0x7D, 0x00, 0x1F, 0xB8, 0x40, 0x04, 0x04, 0x01, 0x00)
+	send(t, conn, frame(true, ProtocolAlarm, alarm, 0x0006))
+	expectAck(t, conn, r, ProtocolAlarm, 0x0006)
+
+	devices.mu.Lock()
+	heartbeats := devices.heartbeats
+	devices.mu.Unlock()
+	// 登录、心跳、未定位的位置包各一次
+	if len(heartbeats) != 3 {
+		t.Fatalf("heartbeats = %d, want 3", len(heartbeats))
+	}
+	for _, h := range heartbeats {
+		if h.deviceID != testIMEI {
+			t.Fatalf("heartbeat device = %q", h.deviceID)
+		}
+	}
+	if heartbeats[1].battery == nil || *heartbeats[1].battery != 50 {
+		t.Fatalf("heartbeat battery = %v, want 50", heartbeats[1].battery)
+	}
+
+	locations.mu.Lock()
+	reports := locations.reports
+	locations.mu.Unlock()
+	if len(reports) != 3 {
+		t.Fatalf("reports = %d, want 3", len(reports))
+	}
+	for i, req := range reports {
+		want := fixAt
+		if i == 1 {
+			want = staleAt
+		}
+		if req.DeviceID != testIMEI || req.BatteryLevel != 50 || !req.RecordedAt.Equal(want) || req.Speed != 10 {
+			t.Fatalf("report %d = %+v", i, req)
+		}
+	}
+	if reports[0].Latitude < 31.23 || reports[0].Longitude < 121.47 || reports[0].Bearing != 90 {
+		t.Fatalf("location report = %+v", reports[0])
+	}
+	if reports[1].Bearing != 45 {
+		t.Fatalf("stale report = %+v", reports[1])
+	}
+	if reports[2].Latitude > -33.86 || reports[2].Bearing != 180 {
+		t.Fatalf("alarm report = %+v", reports[2])
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"packet before login", mustHex(t, heartbeatFrame)},
+		{"unbound device", frame(false, ProtocolLogin, mustHex(t, "0999999999999999"), 0x0001)},
+		{"invalid terminal id", frame(false, ProtocolLogin, mustHex(t, "0123456789ABCDEF"), 0x0001)},
+		{"bad crc", mustHex(t, "78780D01012345678901234500018CDE0D0A")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, locations := newTestServer()
+			conn, r, done := startSession(t, s)
+			send(t, conn, tt.data)
+			expectClosed(t, conn, r, done)
+			if len(locations.reports) != 0 {
+				t.Fatalf("unexpected reports: %d", len(locations.reports))
+			}
+		})
+	}
+}
@@ -133,6 +133,11 @@ func autoMigrate(db *gorm.DB) error {
 		&model.Geofence{},
 		&model.GeofenceEvent{},
 		&model.UserSettings{},
+		&model.RejectedLocation{},
+		&model.Place{},
+		&model.Visit{},
+		&model.DeviceStatusEvent{},
+		&model.BatteryAlert{},
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,7 @@
+-- Polygon / MultiPolygon Geofences (MySQL 8.0+)
+
+-- 多边形围栏的几何数据以 GeoJSON 保存，进出判定在应用层完成；
+-- center / radius_meters 对多边形围栏保存外接圆，便于粗筛。
+ALTER TABLE geofences
+    ADD COLUMN shape_type VARCHAR(20) NOT NULL DEFAULT 'circle' AFTER name,
+    ADD COLUMN geometry JSON NULL AFTER shape_type;
@@ -0,0 +1,16 @@
+-- Rejected Location Fixes
+
+-- 上报时未通过校验的位置点，记录拒绝原因便于排查客户端定位问题
+CREATE TABLE IF NOT EXISTS rejected_locations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT,
+    device_id VARCHAR(64),
+    longitude DOUBLE,
+    latitude DOUBLE,
+    accuracy FLOAT,
+    reason VARCHAR(32) NOT NULL,
+    detail VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_rejected_locations_user_id (user_id),
+    INDEX idx_rejected_locations_created_at (created_at DESC)
+) ENGINE=InnoDB;
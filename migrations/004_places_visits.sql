@@ -0,0 +1,31 @@
+-- Places & Visits (stay-point detection)
+
+-- 常去地点，由多次停留聚类得到
+CREATE TABLE IF NOT EXISTS places (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100),
+    longitude DOUBLE NOT NULL,
+    latitude DOUBLE NOT NULL,
+    visit_count INT NOT NULL DEFAULT 0,
+    total_duration BIGINT NOT NULL DEFAULT 0,
+    last_visit_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_places_user_id (user_id)
+) ENGINE=InnoDB;
+
+-- 停留记录
+CREATE TABLE IF NOT EXISTS visits (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    place_id BIGINT NOT NULL,
+    longitude DOUBLE NOT NULL,
+    latitude DOUBLE NOT NULL,
+    arrived_at TIMESTAMP NOT NULL,
+    departed_at TIMESTAMP NOT NULL,
+    point_count INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX uk_visits_user_arrived (user_id, arrived_at),
+    INDEX idx_visits_place_id (place_id)
+) ENGINE=InnoDB;
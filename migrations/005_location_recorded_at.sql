@@ -0,0 +1,12 @@
+-- Client Recorded Time for User Locations
+
+-- recorded_at 为客户端定位时间，created_at 保留为服务端接收时间；
+-- 位置历史、轨迹等均按 recorded_at 查询，已有记录以接收时间回填。
+ALTER TABLE user_locations
+    ADD COLUMN recorded_at TIMESTAMP NULL AFTER is_low_accuracy;
+
+UPDATE user_locations SET recorded_at = created_at WHERE recorded_at IS NULL;
+
+ALTER TABLE user_locations
+    MODIFY COLUMN recorded_at TIMESTAMP NOT NULL,
+    ADD INDEX idx_user_locations_user_recorded (user_id, recorded_at);
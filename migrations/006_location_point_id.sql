@@ -0,0 +1,7 @@
+-- Idempotent Location Uploads
+
+-- point_id 为客户端生成的位置点ID（或批量上报的幂等键加下标），
+-- 同一用户唯一，用于重试时去重；未提供时为 NULL，不参与唯一约束。
+ALTER TABLE user_locations
+    ADD COLUMN point_id VARCHAR(128) NULL AFTER recorded_at,
+    ADD UNIQUE INDEX uk_user_locations_point (user_id, point_id);
@@ -0,0 +1,5 @@
+-- Device Geofence Events
+
+-- 围栏事件的实体可以是用户或设备，设备ID为字符串，entity_id 改为 VARCHAR 保存两者
+ALTER TABLE geofence_events
+    MODIFY COLUMN entity_id VARCHAR(64) NOT NULL;
@@ -0,0 +1,6 @@
+-- Device API Keys
+
+-- 设备接入密钥仅保存 SHA-256 摘要（十六进制），明文只在绑定或轮换时返回一次；
+-- 为空表示未签发，设备无法通过设备接入接口上报，需先轮换生成。
+ALTER TABLE devices
+    ADD COLUMN api_key_hash CHAR(64) NOT NULL DEFAULT '' AFTER notification_enabled;
@@ -0,0 +1,20 @@
+-- Device Heartbeat and Status Transitions
+
+-- last_seen_at 为设备最近一次位置上报或心跳时间，超过离线阈值未更新的在线设备由后台扫描置为离线
+ALTER TABLE devices
+    ADD COLUMN last_seen_at TIMESTAMP NULL AFTER api_key_hash,
+    ADD COLUMN status_changed_at TIMESTAMP NULL AFTER last_seen_at,
+    ADD INDEX idx_devices_last_seen_at (last_seen_at);
+
+-- 设备连接状态变化记录
+CREATE TABLE IF NOT EXISTS device_status_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    device_id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL COMMENT '变化时的设备绑定者',
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason VARCHAR(20) NOT NULL COMMENT 'report/heartbeat/timeout/manual',
+    last_seen_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_device_status_events_device (device_id, created_at)
+) ENGINE=InnoDB;
@@ -0,0 +1,22 @@
+-- Battery Alerts
+
+-- 低电量告警阈值（百分比），0 表示关闭
+ALTER TABLE devices
+    ADD COLUMN low_battery_threshold INT NOT NULL DEFAULT 20 AFTER notification_enabled;
+
+ALTER TABLE user_settings
+    ADD COLUMN low_battery_threshold INT NOT NULL DEFAULT 20 AFTER distance_unit;
+
+-- 电量告警记录，每个放电周期内同类告警只产生一次
+CREATE TABLE IF NOT EXISTS battery_alerts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL COMMENT '接收告警的用户：用户本人或设备绑定者',
+    entity_type VARCHAR(20) NOT NULL COMMENT 'user/device',
+    entity_id VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL COMMENT 'low_battery/rapid_drain',
+    battery_level INT NOT NULL,
+    threshold INT NOT NULL DEFAULT 0,
+    drain_rate DOUBLE NOT NULL DEFAULT 0 COMMENT '百分比/小时',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_battery_alerts_user (user_id, created_at)
+) ENGINE=InnoDB;
@@ -0,0 +1,12 @@
+-- Recorded Time for Device Locations
+
+-- recorded_at 为设备定位时间（追踪器为 GPS 时间），created_at 保留为服务端接收时间；
+-- 终端补传的历史点按定位时间保存，已有记录以接收时间回填。
+ALTER TABLE device_locations
+    ADD COLUMN recorded_at TIMESTAMP NULL AFTER connection_status;
+
+UPDATE device_locations SET recorded_at = created_at WHERE recorded_at IS NULL;
+
+ALTER TABLE device_locations
+    MODIFY COLUMN recorded_at TIMESTAMP NOT NULL,
+    ADD INDEX idx_device_locations_device_recorded (device_id, recorded_at);
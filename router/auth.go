@@ -12,6 +12,7 @@ import (
 
 type TokenFun func(ctx context.Context, token string) (*common.User, error)
 type TokenAdminFun func(ctx context.Context, token string) (*common.AdminUser, error)
+type DeviceKeyFun func(ctx context.Context, deviceID, key string) (*common.Device, error)
 
 // AuthMiddleware C端用户鉴权中间件
 func AuthMiddleware(filter func(*gin.Context) bool, getTokenFun TokenFun) gin.HandlerFunc {
@@ -80,3 +81,32 @@ func AdminAuthMiddleware(filter func(*gin.Context) bool, getTokenFun TokenAdminF
 		ctx.Next()
 	}
 }
+
+// DeviceAuthMiddleware 设备接入鉴权中间件，校验设备ID与接入密钥
+func DeviceAuthMiddleware(getDeviceFun DeviceKeyFun) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		deviceID := ctx.GetHeader(consts.DeviceIDHeader)
+		key := ctx.GetHeader(consts.DeviceKeyHeader)
+		if len(deviceID) == 0 || len(key) == 0 {
+			ctx.JSON(http.StatusUnauthorized, common.AuthErr)
+			ctx.Abort()
+			return
+		}
+
+		device, err := getDeviceFun(ctx.Request.Context(), deviceID, key)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, common.AuthErr)
+			ctx.Abort()
+			return
+		}
+
+		if device == nil || device.DeviceID == "" {
+			ctx.JSON(http.StatusUnauthorized, common.AuthErr)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(consts.DeviceKey, device)
+		ctx.Next()
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"app/api/customer"
 	"app/common"
 	"app/config"
+	"app/gateway/gt06"
 	"app/utils/logger"
 )
 
@@ -21,6 +22,7 @@ type IRouter interface {
 	Register(engine *gin.Engine)
 	SpanFilter(r *gin.Context) bool
 	AccessRecordFilter(r *gin.Context) bool
+	Servers() []Server
 }
 
 type Router struct {
@@ -31,10 +33,11 @@ type Router struct {
 	admin     *admin.Ctrl
 	customer  *customer.Ctrl
 	verify    appRedis.IVerify
+	servers   []Server
 }
 
 func NewRouter(conf *config.Config, adaptor adaptor.IAdaptor, checkFunc func() error) *Router {
-	return &Router{
+	r := &Router{
 		FullPPROF: conf.Server.EnablePprof,
 		rootPath:  "/api/app",
 		conf:      conf,
@@ -43,6 +46,10 @@ func NewRouter(conf *config.Config, adaptor adaptor.IAdaptor, checkFunc func() e
 		customer:  customer.NewCtrl(adaptor),
 		verify:    appRedis.NewVerify(adaptor.GetRedis()),
 	}
+	if conf.Server.TrackerPort > 0 {
+		r.servers = append(r.servers, gt06.NewServer(conf.Server.TrackerPort, r.customer.Device, r.customer.Location))
+	}
+	return r
 }
 
 func (r *Router) checkServer() func(*gin.Context) {
@@ -78,8 +85,14 @@ func (r *Router) AccessRecordFilter(ctx *gin.Context) bool {
 	return true
 }
 
+// Servers 需要与 HTTP 服务一起运行的其他接入服务
+func (r *Router) Servers() []Server {
+	return r.servers
+}
+
 func (r *Router) route(root *gin.RouterGroup) {
 	r.customerRoute(root)
+	r.deviceRoute(root)
 	r.adminRoute(root)
 }
 
@@ -101,6 +114,11 @@ func (r *Router) validateAdminToken(ctx context.Context, token string) (*common.
 	return &common.AdminUser{UserID: userID}, nil
 }
 
+// validateDeviceKey 验证设备接入密钥
+func (r *Router) validateDeviceKey(ctx context.Context, deviceID, key string) (*common.Device, error) {
+	return r.customer.Device.Authenticate(ctx, deviceID, key)
+}
+
 func (r *Router) customerRoute(root *gin.RouterGroup) {
 	cstRoot := root.Group("/customer", AuthMiddleware(r.SpanFilter, r.validateUserToken))
 
@@ -120,6 +138,13 @@ func (r *Router) customerRoute(root *gin.RouterGroup) {
 		locationGroup.GET("/user/:user_id", r.customer.GetUserLocation)
 		locationGroup.GET("/device/:device_id", r.customer.GetDeviceLocation)
 		locationGroup.GET("/history", r.customer.GetLocationHistory)
+		locationGroup.GET("/trajectory", r.customer.GetTrajectory)
+		locationGroup.GET("/export", r.customer.ExportTrack)
+		locationGroup.POST("/import", r.customer.ImportTrack)
+		locationGroup.GET("/trips", r.customer.GetTrips)
+		locationGroup.GET("/visits", r.customer.GetVisits)
+		locationGroup.GET("/places", r.customer.GetPlaces)
+		locationGroup.PUT("/places/:place_id", r.customer.UpdatePlace)
 		locationGroup.GET("/nearby", r.customer.GetNearbyFriends)
 	}
 
@@ -142,7 +167,17 @@ func (r *Router) customerRoute(root *gin.RouterGroup) {
 		deviceGroup.GET("/list", r.customer.GetDeviceList)
 		deviceGroup.PUT("/:device_id/settings", r.customer.UpdateDeviceSettings)
 		deviceGroup.PUT("/:device_id/status", r.customer.UpdateDeviceStatus)
+		deviceGroup.GET("/:device_id/status/history", r.customer.GetDeviceStatusEvents)
 		deviceGroup.DELETE("/:device_id", r.customer.UnbindDevice)
+		deviceGroup.POST("/:device_id/api-key", r.customer.RotateDeviceAPIKey)
+	}
+
+	// 电量告警相关
+	batteryGroup := cstRoot.Group("/v1/battery")
+	{
+		batteryGroup.GET("/alerts", r.customer.GetBatteryAlerts)
+		batteryGroup.GET("/settings", r.customer.GetBatterySettings)
+		batteryGroup.PUT("/settings", r.customer.UpdateBatterySettings)
 	}
 
 	// 地理围栏相关
@@ -158,6 +193,14 @@ func (r *Router) customerRoute(root *gin.RouterGroup) {
 	cstRoot.GET("/v1/ws", r.customer.WebSocketConnect)
 }
 
+// deviceRoute 设备接入，使用设备密钥鉴权，与用户token相互独立
+func (r *Router) deviceRoute(root *gin.RouterGroup) {
+	devRoot := root.Group("/device", DeviceAuthMiddleware(r.validateDeviceKey))
+
+	devRoot.POST("/v1/location/report", r.customer.DeviceReport)
+	devRoot.POST("/v1/heartbeat", r.customer.DeviceHeartbeat)
+}
+
 func (r *Router) adminRoute(root *gin.RouterGroup) {
 	adminRoot := root.Group("/admin", AdminAuthMiddleware(r.SpanFilter, r.validateAdminToken))
 
@@ -22,6 +22,13 @@ type App struct {
 	server          *gin.Engine
 	addr            string
 	shutdownTimeout time.Duration
+	servers         []Server
+}
+
+// Server 随 HTTP 服务一起启动和优雅关闭的其他服务
+type Server interface {
+	Start() error
+	Shutdown(ctx context.Context) error
 }
 
 func NewApp(port int, shutdownTimeout int, router IRouter) *App {
@@ -46,6 +53,7 @@ func NewApp(port int, shutdownTimeout int, router IRouter) *App {
 		server:          engine,
 		addr:            ":" + strconv.Itoa(port),
 		shutdownTimeout: timeout,
+		servers:         router.Servers(),
 	}
 }
 
@@ -83,6 +91,12 @@ func (app *App) Run() {
 		}
 	}()
 
+	for _, s := range app.servers {
+		if err := s.Start(); err != nil {
+			log.Fatalf("listen err: %v", err)
+		}
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -101,4 +115,9 @@ func (app *App) Run() {
 	} else {
 		logger.Info("server exited gracefully")
 	}
+	for _, s := range app.servers {
+		if err := s.Shutdown(ctx); err != nil {
+			logger.Error("server forced to shutdown", zap.Error(err))
+		}
+	}
 }
@@ -0,0 +1,70 @@
+package battery
+
+import (
+	"time"
+
+	redisCache "app/adaptor/redis"
+)
+
+const (
+	// 电量比本周期最低电量上升超过该值视为充电，开始新的放电周期
+	// 与周期最低电量而不是上一次电量比较，每次只充一点的情况也能识别
+	chargeTolerance = 2
+
+	// 耗电速度按参考点计算：距参考点不足 drainMinWindow 时不判定，超过 drainMaxWindow 时参考点前移
+	drainMinWindow = 15 * time.Minute
+	drainMaxWindow = time.Hour
+	// 耗电速度达到该值（百分比/小时）且期间至少下降 drainMinDrop 时告警
+	drainRateThreshold = 20.0
+	drainMinDrop       = 5
+)
+
+// verdict 一次电量采样的判定结果
+type verdict struct {
+	low       bool
+	drain     bool
+	drainRate float64
+}
+
+// evaluate 根据上次状态判定新的电量采样，返回新状态与需要产生的告警
+// 早于上次采样的电量（乱序到达）不参与判定，返回 nil 状态
+func evaluate(st *redisCache.BatteryState, level int, at time.Time, threshold int) (*redisCache.BatteryState, verdict) {
+	var v verdict
+	if st != nil && at.Before(st.At) {
+		return nil, v
+	}
+
+	next := &redisCache.BatteryState{Level: level, At: at, MinLevel: level, RefLevel: level, RefAt: at}
+	if st != nil {
+		minLevel := st.MinLevel
+		if minLevel <= 0 {
+			// 旧版本状态没有记录最低电量
+			minLevel = st.Level
+		}
+		if level <= minLevel+chargeTolerance {
+			// 仍在同一放电周期内
+			next.MinLevel = min(minLevel, level)
+			next.RefLevel, next.RefAt = st.RefLevel, st.RefAt
+			next.LowAlerted, next.DrainAlerted = st.LowAlerted, st.DrainAlerted
+		}
+	}
+
+	if threshold > 0 && level <= threshold && !next.LowAlerted {
+		v.low = true
+		next.LowAlerted = true
+	}
+
+	if elapsed := at.Sub(next.RefAt); elapsed >= drainMinWindow {
+		drop := next.RefLevel - level
+		rate := float64(drop) / elapsed.Hours()
+		if drop >= drainMinDrop && rate >= drainRateThreshold && !next.DrainAlerted {
+			v.drain = true
+			v.drainRate = rate
+			next.DrainAlerted = true
+		}
+		if elapsed >= drainMaxWindow {
+			next.RefLevel, next.RefAt = level, at
+		}
+	}
+	return next, v
+}
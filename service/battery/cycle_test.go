@@ -0,0 +1,137 @@
+package battery
+
+import (
+	"testing"
+	"time"
+
+	redisCache "app/adaptor/redis"
+)
+
+func TestEvaluate(t *testing.T) {
+	type sample struct {
+		level   int
+		minutes int  // 距第一个采样的分钟数
+		low     bool // 期望产生低电量告警
+		drain   bool // 期望产生耗电过快告警
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		samples   []sample
+	}{
+		{
+			name:      "low once per cycle",
+			threshold: 20,
+			samples: []sample{
+				{level: 30, minutes: 0},
+				{level: 20, minutes: 60, low: true},
+				{level: 20, minutes: 70},
+				{level: 19, minutes: 80},
+				{level: 18, minutes: 90},
+			},
+		},
+		{
+			name:      "gradual charging starts new cycle",
+			threshold: 20,
+			samples: []sample{
+				{level: 20, minutes: 0, low: true},
+				{level: 21, minutes: 10},
+				{level: 22, minutes: 20},
+				{level: 23, minutes: 30},
+				{level: 24, minutes: 40},
+				{level: 20, minutes: 200, low: true},
+			},
+		},
+		{
+			name:      "jump after charging",
+			threshold: 20,
+			samples: []sample{
+				{level: 15, minutes: 0, low: true},
+				{level: 90, minutes: 120},
+				{level: 19, minutes: 900, low: true},
+			},
+		},
+		{
+			name:      "noise within tolerance keeps cycle",
+			threshold: 20,
+			samples: []sample{
+				{level: 19, minutes: 0, low: true},
+				{level: 21, minutes: 10},
+				{level: 18, minutes: 20},
+				{level: 20, minutes: 30},
+			},
+		},
+		{
+			name:      "threshold disabled",
+			threshold: 0,
+			samples: []sample{
+				{level: 10, minutes: 0},
+				{level: 5, minutes: 120},
+			},
+		},
+		{
+			name:      "drain inside window",
+			threshold: 0,
+			samples: []sample{
+				{level: 80, minutes: 0},
+				{level: 76, minutes: 10},
+				{level: 72, minutes: 20, drain: true},
+				{level: 60, minutes: 40},
+			},
+		},
+		{
+			name:      "slow drain",
+			threshold: 0,
+			samples: []sample{
+				{level: 80, minutes: 0},
+				{level: 78, minutes: 30},
+				{level: 75, minutes: 59},
+				{level: 70, minutes: 119},
+			},
+		},
+		{
+			name:      "drain re-arms after charging",
+			threshold: 0,
+			samples: []sample{
+				{level: 80, minutes: 0},
+				{level: 70, minutes: 20, drain: true},
+				{level: 95, minutes: 100},
+				{level: 85, minutes: 120, drain: true},
+			},
+		},
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var st *redisCache.BatteryState
+			for i, s := range tt.samples {
+				next, v := evaluate(st, s.level, base.Add(time.Duration(s.minutes)*time.Minute), tt.threshold)
+				if next == nil {
+					t.Fatalf("sample %d: unexpected nil state", i)
+				}
+				if v.low != s.low || v.drain != s.drain {
+					t.Fatalf("sample %d (level %d): got low=%v drain=%v, want low=%v drain=%v",
+						i, s.level, v.low, v.drain, s.low, s.drain)
+				}
+				st = next
+			}
+		})
+	}
+}
+
+func TestEvaluateOutOfOrder(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	st, _ := evaluate(nil, 50, at, 20)
+	if next, v := evaluate(st, 10, at.Add(-time.Minute), 20); next != nil || v.low || v.drain {
+		t.Fatalf("out of order sample evaluated: %+v %+v", next, v)
+	}
+}
+
+func TestEvaluateLegacyState(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	st := &redisCache.BatteryState{Level: 15, At: at, RefLevel: 15, RefAt: at, LowAlerted: true}
+	if _, v := evaluate(st, 14, at.Add(time.Minute), 20); v.low {
+		t.Fatal("legacy state without min level should stay in the same cycle")
+	}
+}
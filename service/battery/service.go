@@ -0,0 +1,224 @@
+package battery
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	redisCache "app/adaptor/redis"
+	"app/adaptor/repo/battery"
+	"app/adaptor/repo/device"
+	"app/adaptor/repo/model"
+	"app/adaptor/repo/settings"
+	"app/common"
+	"app/service/dto"
+	"app/service/websocket"
+	"app/utils/logger"
+)
+
+const (
+	defaultAlertLimit = 50
+	maxAlertLimit     = 500
+)
+
+// IBatteryService 电量告警服务接口
+type IBatteryService interface {
+	CheckUser(ctx context.Context, userID int64, level int, at time.Time)
+	CheckDevice(ctx context.Context, deviceID string, level int, at time.Time)
+	GetAlerts(ctx context.Context, userID int64, req *dto.BatteryAlertListReq) ([]*dto.BatteryAlertResp, error)
+	GetSettings(ctx context.Context, userID int64) (*dto.BatterySettingsResp, error)
+	UpdateSettings(ctx context.Context, userID int64, req *dto.BatterySettingsReq) (*dto.BatterySettingsResp, error)
+}
+
+// BatteryService 电量告警服务
+//
+// 每个实体（用户手机或设备）按放电周期判定：电量低于阈值、耗电过快各告警一次，
+// 检测到充电后开始新的周期。告警保存后推送给用户本人或设备绑定者。
+type BatteryService struct {
+	repo         *battery.BatteryRepository
+	cache        *redisCache.BatteryCache
+	settingsRepo *settings.SettingsRepository
+	deviceRepo   *device.DeviceRepository
+	hub          *websocket.Hub
+}
+
+// NewBatteryService 创建电量告警服务
+func NewBatteryService(
+	repo *battery.BatteryRepository,
+	cache *redisCache.BatteryCache,
+	settingsRepo *settings.SettingsRepository,
+	deviceRepo *device.DeviceRepository,
+	hub *websocket.Hub,
+) *BatteryService {
+	return &BatteryService{
+		repo:         repo,
+		cache:        cache,
+		settingsRepo: settingsRepo,
+		deviceRepo:   deviceRepo,
+		hub:          hub,
+	}
+}
+
+// CheckUser 判定用户手机电量，失败只记录日志，不影响位置上报
+func (s *BatteryService) CheckUser(ctx context.Context, userID int64, level int, at time.Time) {
+	if !validLevel(level) {
+		return
+	}
+	threshold := model.DefaultLowBatteryThreshold
+	st, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		logger.Warn("battery get settings error", zap.Error(err), zap.Int64("user_id", userID))
+		return
+	}
+	if st != nil {
+		threshold = st.LowBatteryThreshold
+	}
+	s.check(ctx, userID, model.GeofenceEntityUser, strconv.FormatInt(userID, 10), level, at, threshold, true)
+}
+
+// CheckDevice 判定设备电量，设备关闭通知时只保存告警不推送
+// 告警会发给设备绑定者，调用方必须已确认电量来自设备本身（设备密钥、GT06 登录）或绑定者
+func (s *BatteryService) CheckDevice(ctx context.Context, deviceID string, level int, at time.Time) {
+	if !validLevel(level) {
+		return
+	}
+	d, err := s.deviceRepo.Get(ctx, deviceID)
+	if err != nil {
+		logger.Warn("battery get device error", zap.Error(err), zap.String("device_id", deviceID))
+		return
+	}
+	if d == nil {
+		return
+	}
+	s.check(ctx, d.UserID, model.GeofenceEntityDevice, deviceID, level, at, d.LowBatteryThreshold, d.NotificationEnabled)
+}
+
+func (s *BatteryService) check(ctx context.Context, userID int64, entityType, entityID string, level int, at time.Time, threshold int, push bool) {
+	st, err := s.cache.GetState(entityType, entityID)
+	if err != nil {
+		logger.Warn("battery get state error", zap.Error(err), zap.String("entity_id", entityID))
+		return
+	}
+	next, v := evaluate(st, level, at, threshold)
+	if next == nil {
+		return
+	}
+	if err := s.cache.SetState(entityType, entityID, next); err != nil {
+		logger.Warn("battery set state error", zap.Error(err), zap.String("entity_id", entityID))
+		return
+	}
+
+	if v.low {
+		s.alert(ctx, push, &model.BatteryAlert{
+			UserID:       userID,
+			EntityType:   entityType,
+			EntityID:     entityID,
+			Type:         model.BatteryAlertLow,
+			BatteryLevel: level,
+			Threshold:    threshold,
+		})
+	}
+	if v.drain {
+		s.alert(ctx, push, &model.BatteryAlert{
+			UserID:       userID,
+			EntityType:   entityType,
+			EntityID:     entityID,
+			Type:         model.BatteryAlertRapidDrain,
+			BatteryLevel: level,
+			DrainRate:    math.Round(v.drainRate*10) / 10,
+		})
+	}
+}
+
+// alert 保存告警并推送
+func (s *BatteryService) alert(ctx context.Context, push bool, alert *model.BatteryAlert) {
+	if err := s.repo.CreateAlert(ctx, alert); err != nil {
+		logger.Warn("battery create alert error", zap.Error(err), zap.String("entity_id", alert.EntityID))
+		return
+	}
+	if push && s.hub != nil {
+		s.hub.SendToUser(alert.UserID, websocket.NewMessage(websocket.MessageTypeBatteryAlert, toAlertResp(alert)))
+	}
+}
+
+// GetAlerts 获取用户收到的电量告警记录
+func (s *BatteryService) GetAlerts(ctx context.Context, userID int64, req *dto.BatteryAlertListReq) ([]*dto.BatteryAlertResp, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAlertLimit
+	}
+	if limit > maxAlertLimit {
+		limit = maxAlertLimit
+	}
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	alerts, err := s.repo.GetAlerts(ctx, userID, req.EntityType, req.EntityID, limit, offset)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	resp := make([]*dto.BatteryAlertResp, len(alerts))
+	for i, a := range alerts {
+		resp[i] = toAlertResp(a)
+	}
+	return resp, nil
+}
+
+// GetSettings 获取用户手机的电量告警设置
+func (s *BatteryService) GetSettings(ctx context.Context, userID int64) (*dto.BatterySettingsResp, error) {
+	st, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	resp := &dto.BatterySettingsResp{LowBatteryThreshold: model.DefaultLowBatteryThreshold}
+	if st != nil {
+		resp.LowBatteryThreshold = st.LowBatteryThreshold
+	}
+	return resp, nil
+}
+
+// UpdateSettings 更新用户手机的电量告警设置，未保存过设置时按默认设置创建
+func (s *BatteryService) UpdateSettings(ctx context.Context, userID int64, req *dto.BatterySettingsReq) (*dto.BatterySettingsResp, error) {
+	st, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if st == nil {
+		st = &model.UserSettings{
+			UserID:        userID,
+			ShareLocation: true,
+			SmartAlerts:   true,
+			SOSAlerts:     true,
+			MapStyle:      model.MapStyleDark,
+			DistanceUnit:  model.DistanceUnitKm,
+		}
+	}
+	st.LowBatteryThreshold = *req.LowBatteryThreshold
+	if err := s.settingsRepo.Save(ctx, st); err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	return &dto.BatterySettingsResp{LowBatteryThreshold: st.LowBatteryThreshold}, nil
+}
+
+// validLevel 电量为 0 时视为未上报
+func validLevel(level int) bool {
+	return level > 0 && level <= 100
+}
+
+func toAlertResp(a *model.BatteryAlert) *dto.BatteryAlertResp {
+	return &dto.BatteryAlertResp{
+		ID:           a.ID,
+		EntityType:   a.EntityType,
+		EntityID:     a.EntityID,
+		Type:         a.Type,
+		BatteryLevel: a.BatteryLevel,
+		Threshold:    a.Threshold,
+		DrainRate:    a.DrainRate,
+		CreatedAt:    a.CreatedAt,
+	}
+}
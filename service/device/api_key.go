@@ -0,0 +1,86 @@
+package device
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"app/common"
+	"app/service/dto"
+)
+
+const (
+	apiKeyPrefix = "dk_"
+	apiKeyBytes  = 32
+)
+
+// newAPIKey 生成设备接入密钥，返回明文与摘要；明文只返回给用户一次，库中仅保存摘要
+func newAPIKey() (string, string, error) {
+	buf := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(buf)
+	return key, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// RotateAPIKey 为设备重新生成接入密钥，旧密钥立即失效；仅设备绑定者可操作
+func (s *DeviceService) RotateAPIKey(ctx context.Context, userID int64, deviceID string) (*dto.DeviceAPIKeyResp, error) {
+	d, err := s.repo.Get(ctx, deviceID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if d == nil {
+		return nil, common.DeviceNotFoundErr
+	}
+	if d.UserID != userID {
+		return nil, common.PermissionErr
+	}
+
+	key, hash, err := newAPIKey()
+	if err != nil {
+		return nil, common.ServerErr.WithErr(err)
+	}
+	if err := s.repo.UpdateAPIKey(ctx, deviceID, hash); err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	return &dto.DeviceAPIKeyResp{DeviceID: deviceID, APIKey: key}, nil
+}
+
+// Resolve 获取已绑定设备及其绑定者，用于以设备ID识别身份的接入方式（如 GT06 终端的 IMEI）
+func (s *DeviceService) Resolve(ctx context.Context, deviceID string) (*common.Device, error) {
+	d, err := s.repo.Get(ctx, deviceID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if d == nil {
+		return nil, common.DeviceNotFoundErr
+	}
+	return &common.Device{DeviceID: d.ID, UserID: d.UserID}, nil
+}
+
+// Authenticate 校验设备接入密钥，通过后返回设备及其绑定者
+// 设备不存在、未签发密钥或密钥不匹配均返回 AuthErr，不区分原因
+func (s *DeviceService) Authenticate(ctx context.Context, deviceID, key string) (*common.Device, error) {
+	if deviceID == "" || key == "" {
+		return nil, common.AuthErr
+	}
+	d, err := s.repo.Get(ctx, deviceID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if d == nil || d.APIKeyHash == "" {
+		return nil, common.AuthErr
+	}
+	if subtle.ConstantTimeCompare([]byte(d.APIKeyHash), []byte(hashAPIKey(key))) != 1 {
+		return nil, common.AuthErr
+	}
+	return &common.Device{DeviceID: d.ID, UserID: d.UserID}, nil
+}
@@ -2,41 +2,63 @@ package device
 
 import (
 	"context"
+	"time"
 
 	"app/adaptor/repo/device"
 	"app/adaptor/repo/model"
 	"app/common"
+	"app/service/battery"
 	"app/service/dto"
+	"app/service/websocket"
 )
 
 // IDeviceService 设备服务接口
 type IDeviceService interface {
-	BindDevice(ctx context.Context, userID int64, req *dto.DeviceBindReq) error
+	BindDevice(ctx context.Context, userID int64, req *dto.DeviceBindReq) (*dto.DeviceAPIKeyResp, error)
 	UnbindDevice(ctx context.Context, userID int64, deviceID string) error
 	GetDeviceList(ctx context.Context, userID int64) ([]*dto.DeviceResp, error)
 	UpdateDeviceSettings(ctx context.Context, userID int64, deviceID string, req *dto.DeviceSettingsReq) error
-	UpdateDeviceStatus(ctx context.Context, deviceID string, req *dto.DeviceStatusReq) error
+	UpdateDeviceStatus(ctx context.Context, userID int64, deviceID string, req *dto.DeviceStatusReq) error
+	RotateAPIKey(ctx context.Context, userID int64, deviceID string) (*dto.DeviceAPIKeyResp, error)
+	Authenticate(ctx context.Context, deviceID, key string) (*common.Device, error)
+	Resolve(ctx context.Context, deviceID string) (*common.Device, error)
+	Touch(ctx context.Context, deviceID string, batteryLevel *int, reason string) error
+	Heartbeat(ctx context.Context, device *common.Device, req *dto.DeviceHeartbeatReq) error
+	GetStatusEvents(ctx context.Context, userID int64, deviceID string, limit int) ([]*dto.DeviceStatusEventResp, error)
 }
 
 // DeviceService 设备服务实现
 type DeviceService struct {
-	repo *device.DeviceRepository
+	repo    *device.DeviceRepository
+	hub     *websocket.Hub
+	battery *battery.BatteryService
+
+	// 超过该时长没有位置上报或心跳的在线设备置为离线
+	offlineTimeout time.Duration
 }
 
-// NewDeviceService 创建设备服务
-func NewDeviceService(repo *device.DeviceRepository) *DeviceService {
-	return &DeviceService{repo: repo}
+// NewDeviceService 创建设备服务，offlineTimeout 不大于 0 时使用默认离线阈值
+func NewDeviceService(repo *device.DeviceRepository, hub *websocket.Hub, battery *battery.BatteryService, offlineTimeout time.Duration) *DeviceService {
+	if offlineTimeout <= 0 {
+		offlineTimeout = defaultOfflineTimeout
+	}
+	return &DeviceService{repo: repo, hub: hub, battery: battery, offlineTimeout: offlineTimeout}
 }
 
-// BindDevice 绑定设备
-func (s *DeviceService) BindDevice(ctx context.Context, userID int64, req *dto.DeviceBindReq) error {
+// BindDevice 绑定设备，同时签发设备接入密钥
+func (s *DeviceService) BindDevice(ctx context.Context, userID int64, req *dto.DeviceBindReq) (*dto.DeviceAPIKeyResp, error) {
 	// 检查设备是否已被绑定
 	bound, err := s.repo.IsBound(ctx, req.DeviceID)
 	if err != nil {
-		return common.DatabaseErr.WithErr(err)
+		return nil, common.DatabaseErr.WithErr(err)
 	}
 	if bound {
-		return common.DeviceAlreadyBoundErr
+		return nil, common.DeviceAlreadyBoundErr
+	}
+
+	key, hash, err := newAPIKey()
+	if err != nil {
+		return nil, common.ServerErr.WithErr(err)
 	}
 
 	deviceType := req.Type
@@ -51,12 +73,16 @@ func (s *DeviceService) BindDevice(ctx context.Context, userID int64, req *dto.D
 		Type:                deviceType,
 		ConnectionStatus:    model.DeviceConnectionUnknown,
 		NotificationEnabled: true,
+		APIKeyHash:          hash,
 	}
 
-	return s.repo.Create(ctx, d)
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	return &dto.DeviceAPIKeyResp{DeviceID: d.ID, APIKey: key}, nil
 }
 
-// UnbindDevice 解绑设备
+// UnbindDevice 解绑设备，设备记录删除后接入密钥随之失效
 func (s *DeviceService) UnbindDevice(ctx context.Context, userID int64, deviceID string) error {
 	// 检查设备是否存在
 	d, err := s.repo.Get(ctx, deviceID)
@@ -91,6 +117,8 @@ func (s *DeviceService) GetDeviceList(ctx context.Context, userID int64) ([]*dto
 			BatteryLevel:        d.BatteryLevel,
 			ConnectionStatus:    string(d.ConnectionStatus),
 			NotificationEnabled: d.NotificationEnabled,
+			LowBatteryThreshold: d.LowBatteryThreshold,
+			LastSeenAt:          d.LastSeenAt,
 			CreatedAt:           d.CreatedAt,
 		}
 	}
@@ -111,22 +139,43 @@ func (s *DeviceService) UpdateDeviceSettings(ctx context.Context, userID int64,
 		return common.PermissionErr
 	}
 
+	var name *string
 	if req.Name != "" {
-		d.Name = req.Name
+		name = &req.Name
 	}
-	if req.NotificationEnabled != nil {
-		d.NotificationEnabled = *req.NotificationEnabled
+	if err := s.repo.UpdateSettings(ctx, deviceID, name, req.NotificationEnabled, req.LowBatteryThreshold); err != nil {
+		return common.DatabaseErr.WithErr(err)
 	}
-
-	return s.repo.Update(ctx, d)
+	return nil
 }
 
 // UpdateDeviceStatus 更新设备状态
-func (s *DeviceService) UpdateDeviceStatus(ctx context.Context, deviceID string, req *dto.DeviceStatusReq) error {
+// 仅设备绑定者可操作；置为在线视同一次心跳，状态发生变化时记录并推送给绑定者
+func (s *DeviceService) UpdateDeviceStatus(ctx context.Context, userID int64, deviceID string, req *dto.DeviceStatusReq) error {
+	d, err := s.repo.Get(ctx, deviceID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if d == nil {
+		return common.DeviceNotFoundErr
+	}
+	if d.UserID != userID {
+		return common.PermissionErr
+	}
+
 	status := model.DeviceConnectionStatus(req.ConnectionStatus)
 	if status == "" {
 		status = model.DeviceConnectionUnknown
 	}
 
-	return s.repo.UpdateStatus(ctx, deviceID, req.BatteryLevel, status)
+	if status == model.DeviceConnectionOnline {
+		return s.Touch(ctx, deviceID, &req.BatteryLevel, model.DeviceStatusReasonManual)
+	}
+	now := time.Now()
+	if err := s.repo.UpdateBattery(ctx, deviceID, req.BatteryLevel); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	// 已确认调用者为绑定者，电量告警只会发给本人
+	s.battery.CheckDevice(ctx, deviceID, req.BatteryLevel, now)
+	return s.transit(ctx, deviceID, status, model.DeviceStatusReasonManual, now)
 }
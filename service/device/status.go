@@ -0,0 +1,150 @@
+package device
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"app/adaptor/repo/model"
+	"app/common"
+	"app/service/dto"
+	"app/service/websocket"
+	"app/utils/logger"
+)
+
+const (
+	// 未配置时的离线阈值
+	defaultOfflineTimeout = 5 * time.Minute
+
+	// 超时离线扫描
+	sweepInterval  = 30 * time.Second
+	sweepBatchSize = 100
+
+	defaultStatusEventLimit = 50
+	maxStatusEventLimit     = 500
+)
+
+// Touch 记录设备位置上报或心跳，设备不在线时置为在线
+// batteryLevel 为空时不更新电量，否则同时判定电量告警；调用方必须已确认设备身份或绑定关系
+func (s *DeviceService) Touch(ctx context.Context, deviceID string, batteryLevel *int, reason string) error {
+	now := time.Now()
+	if err := s.repo.Touch(ctx, deviceID, batteryLevel, now); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if batteryLevel != nil {
+		s.battery.CheckDevice(ctx, deviceID, *batteryLevel, now)
+	}
+	return s.transit(ctx, deviceID, model.DeviceConnectionOnline, reason, now)
+}
+
+// Heartbeat 设备心跳
+func (s *DeviceService) Heartbeat(ctx context.Context, device *common.Device, req *dto.DeviceHeartbeatReq) error {
+	return s.Touch(ctx, device.DeviceID, req.BatteryLevel, model.DeviceStatusReasonHeartbeat)
+}
+
+// GetStatusEvents 获取设备连接状态变化记录，仅设备绑定者可查看
+func (s *DeviceService) GetStatusEvents(ctx context.Context, userID int64, deviceID string, limit int) ([]*dto.DeviceStatusEventResp, error) {
+	d, err := s.repo.Get(ctx, deviceID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if d == nil {
+		return nil, common.DeviceNotFoundErr
+	}
+	if d.UserID != userID {
+		return nil, common.PermissionErr
+	}
+
+	if limit <= 0 {
+		limit = defaultStatusEventLimit
+	}
+	if limit > maxStatusEventLimit {
+		limit = maxStatusEventLimit
+	}
+	events, err := s.repo.GetStatusEvents(ctx, deviceID, limit)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	resp := make([]*dto.DeviceStatusEventResp, len(events))
+	for i, e := range events {
+		resp[i] = &dto.DeviceStatusEventResp{
+			ID:         e.ID,
+			DeviceID:   e.DeviceID,
+			FromStatus: string(e.FromStatus),
+			ToStatus:   string(e.ToStatus),
+			Reason:     e.Reason,
+			LastSeenAt: e.LastSeenAt,
+			CreatedAt:  e.CreatedAt,
+		}
+	}
+	return resp, nil
+}
+
+// Run 定期将超过离线阈值没有上报或心跳的在线设备置为离线
+func (s *DeviceService) Run() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+func (s *DeviceService) sweep() {
+	ctx := context.Background()
+	now := time.Now()
+	staleBefore := now.Add(-s.offlineTimeout)
+
+	for {
+		devices, err := s.repo.GetStale(ctx, staleBefore, sweepBatchSize)
+		if err != nil {
+			logger.Warn("device sweep error", zap.Error(err))
+			return
+		}
+		marked := 0
+		for _, d := range devices {
+			event, err := s.repo.MarkOffline(ctx, d.ID, staleBefore, now)
+			if err != nil {
+				logger.Warn("device mark offline error", zap.Error(err), zap.String("device_id", d.ID))
+				continue
+			}
+			if event != nil {
+				marked++
+				s.notify(event)
+			}
+		}
+		// 本批没有进展时留到下一轮，避免反复取到同一批设备
+		if len(devices) < sweepBatchSize || marked == 0 {
+			return
+		}
+	}
+}
+
+// transit 切换设备连接状态，状态发生变化时推送给绑定者
+func (s *DeviceService) transit(ctx context.Context, deviceID string, to model.DeviceConnectionStatus, reason string, at time.Time) error {
+	event, err := s.repo.TransitStatus(ctx, deviceID, to, reason, at)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if event != nil {
+		s.notify(event)
+	}
+	return nil
+}
+
+// notify 向设备绑定者推送连接状态变化
+func (s *DeviceService) notify(event *model.DeviceStatusEvent) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.SendToUser(event.UserID, websocket.NewMessage(websocket.MessageTypeDeviceStatus, &dto.DeviceStatusChangedEvent{
+		DeviceID:   event.DeviceID,
+		Status:     string(event.ToStatus),
+		FromStatus: string(event.FromStatus),
+		Reason:     event.Reason,
+		LastSeenAt: event.LastSeenAt,
+		ChangedAt:  event.CreatedAt,
+	}))
+}
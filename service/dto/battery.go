@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// BatteryAlertListReq 电量告警记录查询请求
+type BatteryAlertListReq struct {
+	EntityType string `json:"entity_type"` // 可选，user 或 device
+	EntityID   string `json:"entity_id"`   // 可选，用户ID或设备ID
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+}
+
+// BatteryAlertResp 电量告警，同时用于 WebSocket 推送
+type BatteryAlertResp struct {
+	ID           int64     `json:"id"`
+	EntityType   string    `json:"entity_type"`
+	EntityID     string    `json:"entity_id"`
+	Type         string    `json:"type"` // low_battery, rapid_drain
+	BatteryLevel int       `json:"battery_level"`
+	Threshold    int       `json:"threshold,omitempty"`  // 低电量阈值
+	DrainRate    float64   `json:"drain_rate,omitempty"` // 耗电速度（百分比/小时）
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BatterySettingsReq 用户手机电量告警设置请求
+type BatterySettingsReq struct {
+	LowBatteryThreshold *int `json:"low_battery_threshold" binding:"required,min=0,max=100"` // 0 表示关闭低电量告警
+}
+
+// BatterySettingsResp 用户手机电量告警设置
+type BatterySettingsResp struct {
+	LowBatteryThreshold int `json:"low_battery_threshold"`
+}
@@ -9,6 +9,12 @@ type DeviceBindReq struct {
 	Type     string `json:"type"` // phone, tablet, watch, tracker, other
 }
 
+// DeviceAPIKeyResp 设备接入密钥，明文仅在绑定和轮换时返回一次
+type DeviceAPIKeyResp struct {
+	DeviceID string `json:"device_id"`
+	APIKey   string `json:"api_key"`
+}
+
 // DeviceResp 设备响应
 type DeviceResp struct {
 	ID                  string `json:"id"`
@@ -17,6 +23,8 @@ type DeviceResp struct {
 	BatteryLevel        int    `json:"battery_level"`
 	ConnectionStatus    string `json:"connection_status"`
 	NotificationEnabled bool   `json:"notification_enabled"`
+	LowBatteryThreshold int    `json:"low_battery_threshold"`
+	LastSeenAt          *time.Time `json:"last_seen_at,omitempty"` // 最近一次位置上报或心跳时间
 	Location            *LocationResp `json:"location,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
 }
@@ -25,6 +33,7 @@ type DeviceResp struct {
 type DeviceSettingsReq struct {
 	Name                string `json:"name"`
 	NotificationEnabled *bool  `json:"notification_enabled"`
+	LowBatteryThreshold *int   `json:"low_battery_threshold" binding:"omitempty,min=0,max=100"` // 0 表示关闭低电量告警
 }
 
 // DeviceStatusReq 设备状态更新请求
@@ -32,3 +41,29 @@ type DeviceStatusReq struct {
 	BatteryLevel     int    `json:"battery_level"`
 	ConnectionStatus string `json:"connection_status"` // online, offline, unknown
 }
+
+// DeviceHeartbeatReq 设备心跳请求
+type DeviceHeartbeatReq struct {
+	BatteryLevel *int `json:"battery_level" binding:"omitempty,min=0,max=100"` // 可选，为空时不更新电量
+}
+
+// DeviceStatusEventResp 设备连接状态变化记录
+type DeviceStatusEventResp struct {
+	ID         int64      `json:"id"`
+	DeviceID   string     `json:"device_id"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	Reason     string     `json:"reason"` // report, heartbeat, timeout, manual
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DeviceStatusChangedEvent 设备连接状态变化推送
+type DeviceStatusChangedEvent struct {
+	DeviceID   string     `json:"device_id"`
+	Status     string     `json:"status"`
+	FromStatus string     `json:"from_status"`
+	Reason     string     `json:"reason"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
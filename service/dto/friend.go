@@ -22,6 +22,7 @@ type FriendResp struct {
 	Avatar        string    `json:"avatar"`
 	Status        string    `json:"status"`         // pending, accepted, rejected
 	SharingStatus string    `json:"sharing_status"` // sharing, paused, hidden
+	Online        bool      `json:"online"`
 	LastActive    time.Time `json:"last_active"`
 	Location      *LocationResp `json:"location,omitempty"`
 }
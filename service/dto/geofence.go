@@ -1,38 +1,49 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // GeofenceCreateReq 创建地理围栏请求
+// 圆形围栏使用 center_lon/center_lat/radius_meters，多边形围栏使用 wkt 或 geojson 之一
 type GeofenceCreateReq struct {
-	Name          string  `json:"name" binding:"required"`
-	CenterLon     float64 `json:"center_lon" binding:"required"`
-	CenterLat     float64 `json:"center_lat" binding:"required"`
-	RadiusMeters  float64 `json:"radius_meters" binding:"required,gt=0"`
-	NotifyOnEnter bool    `json:"notify_on_enter"`
-	NotifyOnExit  bool    `json:"notify_on_exit"`
+	Name          string          `json:"name" binding:"required"`
+	ShapeType     string          `json:"shape_type"` // circle, polygon, multipolygon，为空时根据参数推断
+	CenterLon     float64         `json:"center_lon"`
+	CenterLat     float64         `json:"center_lat"`
+	RadiusMeters  float64         `json:"radius_meters" binding:"gte=0"`
+	WKT           string          `json:"wkt"`
+	GeoJSON       json.RawMessage `json:"geojson" swaggertype:"object"`
+	NotifyOnEnter bool            `json:"notify_on_enter"`
+	NotifyOnExit  bool            `json:"notify_on_exit"`
 }
 
 // GeofenceUpdateReq 更新地理围栏请求
 type GeofenceUpdateReq struct {
-	Name          string  `json:"name"`
-	RadiusMeters  float64 `json:"radius_meters"`
-	NotifyOnEnter *bool   `json:"notify_on_enter"`
-	NotifyOnExit  *bool   `json:"notify_on_exit"`
-	IsActive      *bool   `json:"is_active"`
+	Name          string          `json:"name"`
+	RadiusMeters  float64         `json:"radius_meters"`
+	WKT           string          `json:"wkt"`
+	GeoJSON       json.RawMessage `json:"geojson" swaggertype:"object"`
+	NotifyOnEnter *bool           `json:"notify_on_enter"`
+	NotifyOnExit  *bool           `json:"notify_on_exit"`
+	IsActive      *bool           `json:"is_active"`
 }
 
 // GeofenceResp 地理围栏响应
 type GeofenceResp struct {
-	ID            int64     `json:"id"`
-	Name          string    `json:"name"`
-	CenterLon     float64   `json:"center_lon"`
-	CenterLat     float64   `json:"center_lat"`
-	RadiusMeters  float64   `json:"radius_meters"`
-	NotifyOnEnter bool      `json:"notify_on_enter"`
-	NotifyOnExit  bool      `json:"notify_on_exit"`
-	IsActive      bool      `json:"is_active"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int64           `json:"id"`
+	Name          string          `json:"name"`
+	ShapeType     string          `json:"shape_type"`
+	CenterLon     float64         `json:"center_lon"`
+	CenterLat     float64         `json:"center_lat"`
+	RadiusMeters  float64         `json:"radius_meters"`
+	Geometry      json.RawMessage `json:"geometry,omitempty" swaggertype:"object"` // GeoJSON，仅多边形围栏
+	NotifyOnEnter bool            `json:"notify_on_enter"`
+	NotifyOnExit  bool            `json:"notify_on_exit"`
+	IsActive      bool            `json:"is_active"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 // GeofenceEventResp 地理围栏事件响应
@@ -41,7 +52,7 @@ type GeofenceEventResp struct {
 	GeofenceID  int64     `json:"geofence_id"`
 	GeofenceName string  `json:"geofence_name"`
 	EntityType  string    `json:"entity_type"`
-	EntityID    int64     `json:"entity_id"`
+	EntityID    string    `json:"entity_id"`
 	EventType   string    `json:"event_type"` // enter, exit
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,39 @@
+package dto
+
+// 输出格式
+const (
+	FormatJSON     = "json"
+	FormatGeoJSON  = "geojson"
+	FormatPolyline = "polyline"
+	FormatGPX      = "gpx"
+	FormatKML      = "kml"
+)
+
+// GeoJSONFeatureCollection GeoJSON 要素集合
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"` // FeatureCollection
+	Features []*GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature GeoJSON 要素
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"` // Feature
+	Geometry   *GeoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry GeoJSON 几何对象，坐标顺序为 [经度, 纬度]
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"` // Point, LineString
+	Coordinates interface{} `json:"coordinates" swaggertype:"array,number"`
+}
+
+// EncodedPolylineResp 编码折线格式的轨迹
+type EncodedPolylineResp struct {
+	Polyline   string  `json:"polyline"`   // Google Encoded Polyline，按时间正序
+	Precision  int     `json:"precision"`  // 坐标精度（小数位数）
+	Timestamps []int64 `json:"timestamps"` // 各点的 Unix 时间戳（秒），与折线中的点一一对应
+
+	Distance *float64 `json:"distance,omitempty"` // 轨迹总距离（米），仅轨迹接口返回
+	Duration *int64   `json:"duration,omitempty"` // 轨迹总时长（秒），仅轨迹接口返回
+}
@@ -25,11 +25,31 @@ type LocationReportReq struct {
 	LocationMode  LocationMode `json:"location_mode"`
 	IsLowAccuracy bool         `json:"is_low_accuracy"`
 	DeviceID      string       `json:"device_id"` // 可选，设备上报时使用
+	RecordedAt    time.Time    `json:"recorded_at"` // 可选，客户端定位时间，为空时使用服务端接收时间
+	PointID       string       `json:"point_id" binding:"max=64"` // 可选，客户端生成的位置点ID，重试时用于去重
 }
 
 // BatchLocationReportReq 批量位置上报请求
 type BatchLocationReportReq struct {
 	Locations []LocationReportReq `json:"locations" binding:"required,dive"`
+	// 可选，批次幂等键，HTTP 接口也可通过 Idempotency-Key 请求头传入
+	// 未提供 point_id 的点以“幂等键:下标”作为位置点ID
+	IdempotencyKey string `json:"idempotency_key" binding:"max=64"`
+}
+
+// BatchLocationReportResp 批量位置上报响应
+type BatchLocationReportResp struct {
+	Count      int                    `json:"count"`      // 新保存的位置点数
+	Stored     []int                  `json:"stored"`     // 新保存的位置点在请求 locations 中的下标
+	Duplicates int                    `json:"duplicates"` // 重复而跳过的点数
+	Duplicated []int                  `json:"duplicated"` // 重复的位置点下标：位置点ID已保存过，或批次内位置点ID、定位时间重复
+	Rejected   []RejectedLocationResp `json:"rejected"`   // 被拒绝的位置点
+}
+
+// RejectedLocationResp 被拒绝的位置点
+type RejectedLocationResp struct {
+	Index  int    `json:"index"`  // 在请求 locations 中的下标
+	Reason string `json:"reason"` // invalid_coordinate, null_island, invalid_accuracy, impossible_speed, invalid_timestamp
 }
 
 // LocationResp 位置响应
@@ -45,18 +65,60 @@ type LocationResp struct {
 	Bearing      float64     `json:"bearing"`
 	BatteryLevel int         `json:"battery_level"`
 	LocationMode LocationMode `json:"location_mode,omitempty"`
-	CreatedAt    time.Time   `json:"created_at"`
+	RecordedAt   time.Time   `json:"recorded_at"` // 定位时间
+	CreatedAt    time.Time   `json:"created_at"`  // 服务端接收时间
+	Presence     *PresenceResp `json:"presence,omitempty"` // 仅查询用户位置时返回
 }
 
 // LocationHistoryReq 位置历史查询请求
 type LocationHistoryReq struct {
-	UserID   int64     `json:"user_id" binding:"required"`
+	UserID   int64     `json:"user_id"` // 为空时查询本人
 	StartTime time.Time `json:"start_time" binding:"required"`
 	EndTime   time.Time `json:"end_time" binding:"required"`
 	Limit    int       `json:"limit"`
 	Offset   int       `json:"offset"`
 }
 
+// TrajectoryReq 轨迹查询请求
+type TrajectoryReq struct {
+	UserID    int64     `json:"user_id"` // 为空时查询本人
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	Tolerance float64   `json:"tolerance"` // 简化容差（米），默认 10
+	Interval  int       `json:"interval"`  // 按时间分桶降采样的间隔（秒），为 0 时不降采样
+}
+
+// TrajectoryPoint 轨迹点
+type TrajectoryPoint struct {
+	Longitude float64   `json:"longitude"`
+	Latitude  float64   `json:"latitude"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TrajectoryResp 轨迹响应
+type TrajectoryResp struct {
+	Points        []TrajectoryPoint `json:"points"`         // 简化后的轨迹点
+	Distance      float64           `json:"distance"`       // 总距离（米），按简化前的轨迹计算
+	Duration      int64             `json:"duration"`       // 总时长（秒）
+	OriginalCount int               `json:"original_count"` // 简化前的点数
+}
+
+// TrackExportReq 轨迹导出请求
+type TrackExportReq struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+	Format    string    `json:"format"` // gpx（默认）, kml
+}
+
+// LocationImportResp 位置导入结果
+type LocationImportResp struct {
+	Total      int            `json:"total"`      // 文件中的位置点数
+	Accepted   int            `json:"accepted"`   // 导入成功的点数
+	Duplicates int            `json:"duplicates"` // 与已有记录时间重复而跳过的点数
+	Rejected   int            `json:"rejected"`   // 未通过校验的点数
+	Reasons    map[string]int `json:"reasons"`    // 各拒绝原因的点数
+}
+
 // NearbyFriendResp 附近好友响应
 type NearbyFriendResp struct {
 	UserID       int64       `json:"user_id"`
@@ -66,5 +128,6 @@ type NearbyFriendResp struct {
 	Latitude     float64     `json:"latitude"`
 	Distance     float64     `json:"distance"` // 距离（米）
 	BatteryLevel int         `json:"battery_level"`
+	Online       bool        `json:"online"`
 	LastActive   time.Time   `json:"last_active"`
 }
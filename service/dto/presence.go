@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+// PresenceResp 在线状态
+type PresenceResp struct {
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen,omitempty"` // 最后活跃时间
+}
+
+// PresenceChangedEvent 在线状态变化推送
+type PresenceChangedEvent struct {
+	UserID   int64     `json:"user_id"`
+	Online   bool      `json:"online"`
+	LastSeen time.Time `json:"last_seen"`
+}
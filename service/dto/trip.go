@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+// MovementMode 移动方式
+type MovementMode string
+
+const (
+	MovementModeStationary MovementMode = "stationary"
+	MovementModeWalking    MovementMode = "walking"
+	MovementModeCycling    MovementMode = "cycling"
+	MovementModeDriving    MovementMode = "driving"
+)
+
+// TripListReq 行程查询请求
+type TripListReq struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// TripResp 行程摘要
+type TripResp struct {
+	StartTime      time.Time    `json:"start_time"`
+	EndTime        time.Time    `json:"end_time"`
+	StartLongitude float64      `json:"start_longitude"`
+	StartLatitude  float64      `json:"start_latitude"`
+	EndLongitude   float64      `json:"end_longitude"`
+	EndLatitude    float64      `json:"end_latitude"`
+	Distance       float64      `json:"distance"`  // 距离（米）
+	Duration       int64        `json:"duration"`  // 时长（秒）
+	MaxSpeed       float64      `json:"max_speed"` // 最大速度（米/秒）
+	AvgSpeed       float64      `json:"avg_speed"` // 平均速度（米/秒），距离除以时长
+	Mode           MovementMode `json:"mode"`
+	PointCount     int          `json:"point_count"`
+}
@@ -0,0 +1,39 @@
+package dto
+
+import "time"
+
+// VisitListReq 停留记录查询请求
+type VisitListReq struct {
+	StartTime time.Time `json:"start_time"` // 默认结束时间前 30 天
+	EndTime   time.Time `json:"end_time"`   // 默认当前时间
+	Limit     int       `json:"limit"`
+	Offset    int       `json:"offset"`
+}
+
+// VisitResp 停留记录响应
+type VisitResp struct {
+	ID         int64     `json:"id"`
+	PlaceID    int64     `json:"place_id"`
+	PlaceName  string    `json:"place_name"`
+	Longitude  float64   `json:"longitude"`
+	Latitude   float64   `json:"latitude"`
+	ArrivedAt  time.Time `json:"arrived_at"`
+	DepartedAt time.Time `json:"departed_at"`
+	Duration   int64     `json:"duration"` // 停留时长（秒）
+}
+
+// PlaceResp 地点响应
+type PlaceResp struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Longitude     float64   `json:"longitude"`
+	Latitude      float64   `json:"latitude"`
+	VisitCount    int       `json:"visit_count"`
+	TotalDuration int64     `json:"total_duration"` // 累计停留时长（秒）
+	LastVisitAt   time.Time `json:"last_visit_at"`
+}
+
+// PlaceUpdateReq 地点更新请求
+type PlaceUpdateReq struct {
+	Name string `json:"name" binding:"max=100"`
+}
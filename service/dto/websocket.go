@@ -0,0 +1,36 @@
+package dto
+
+// WSEntity WebSocket 订阅实体
+type WSEntity struct {
+	Type string `json:"type"` // user, device
+	ID   string `json:"id"`
+}
+
+// WSSubscribeReq 订阅/取消订阅请求
+type WSSubscribeReq struct {
+	Entities []WSEntity `json:"entities"`
+}
+
+// WSRejectedEntity 被拒绝的订阅实体
+type WSRejectedEntity struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// WSSubscribeAck 订阅/取消订阅应答
+type WSSubscribeAck struct {
+	Accepted []WSEntity         `json:"accepted"`
+	Rejected []WSRejectedEntity `json:"rejected"`
+}
+
+// WSReportAck 位置上报应答
+type WSReportAck struct {
+	Count int `json:"count"`
+}
+
+// WSError 请求处理失败时的错误应答，code 与 HTTP 接口的错误码一致
+type WSError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 
+	"go.uber.org/zap"
+
 	"app/adaptor/repo/friend"
 	"app/adaptor/repo/model"
 	"app/common"
 	"app/service/dto"
+	"app/service/presence"
+	"app/utils/logger"
 )
 
 // IFriendService 好友服务接口
@@ -23,12 +27,13 @@ type IFriendService interface {
 
 // FriendService 好友服务实现
 type FriendService struct {
-	repo *friend.FriendRepository
+	repo     *friend.FriendRepository
+	presence *presence.PresenceService
 }
 
 // NewFriendService 创建好友服务
-func NewFriendService(repo *friend.FriendRepository) *FriendService {
-	return &FriendService{repo: repo}
+func NewFriendService(repo *friend.FriendRepository, presence *presence.PresenceService) *FriendService {
+	return &FriendService{repo: repo, presence: presence}
 }
 
 // SendFriendRequest 发送好友请求
@@ -130,6 +135,16 @@ func (s *FriendService) GetFriendList(ctx context.Context, userID int64) ([]*dto
 		return nil, common.DatabaseErr.WithErr(err)
 	}
 
+	friendIDs := make([]int64, len(friends))
+	for i, f := range friends {
+		friendIDs[i] = f.FriendID
+	}
+	// 在线状态获取失败时按离线展示
+	presences, err := s.presence.GetPresences(ctx, userID, friendIDs)
+	if err != nil {
+		logger.Warn("get presence failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+
 	resp := make([]*dto.FriendResp, len(friends))
 	for i, f := range friends {
 		resp[i] = &dto.FriendResp{
@@ -141,6 +156,12 @@ func (s *FriendService) GetFriendList(ctx context.Context, userID int64) ([]*dto
 			Nickname: fmt.Sprintf("好友%d", f.FriendID),
 			Avatar:   "",
 		}
+		if p, ok := presences[f.FriendID]; ok {
+			resp[i].Online = p.Online
+			if p.LastSeen != nil {
+				resp[i].LastActive = *p.LastSeen
+			}
+		}
 	}
 
 	return resp, nil
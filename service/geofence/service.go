@@ -2,11 +2,26 @@ package geofence
 
 import (
 	"context"
+	"encoding/json"
+	"math"
 
+	"go.uber.org/zap"
+
+	redisCache "app/adaptor/redis"
 	"app/adaptor/repo/geofence"
 	"app/adaptor/repo/model"
 	"app/common"
 	"app/service/dto"
+	"app/utils/geo"
+	"app/utils/logger"
+)
+
+const (
+	// 边界缓冲区（米），取定位精度并限制在该区间内，避免 GPS 漂移在边界反复触发事件
+	minHysteresisMeters = 10
+	maxHysteresisMeters = 200
+	// 精度差于该值的定位不参与围栏判定
+	maxEvaluableAccuracy = 1000
 )
 
 // IGeofenceService 地理围栏服务接口
@@ -15,17 +30,18 @@ type IGeofenceService interface {
 	GetList(ctx context.Context, userID int64) ([]*dto.GeofenceResp, error)
 	Update(ctx context.Context, userID int64, geofenceID int64, req *dto.GeofenceUpdateReq) error
 	Delete(ctx context.Context, userID int64, geofenceID int64) error
-	CheckGeofenceEvents(ctx context.Context, lon, lat float64, entityType string, entityID int64) error
+	CheckGeofenceEvents(ctx context.Context, ownerID int64, lon, lat, accuracy float64, entityType, entityID string) ([]*model.GeofenceEvent, error)
 }
 
 // GeofenceService 地理围栏服务实现
 type GeofenceService struct {
-	repo *geofence.GeofenceRepository
+	repo  *geofence.GeofenceRepository
+	cache *redisCache.GeofenceCache
 }
 
 // NewGeofenceService 创建地理围栏服务
-func NewGeofenceService(repo *geofence.GeofenceRepository) *GeofenceService {
-	return &GeofenceService{repo: repo}
+func NewGeofenceService(repo *geofence.GeofenceRepository, cache *redisCache.GeofenceCache) *GeofenceService {
+	return &GeofenceService{repo: repo, cache: cache}
 }
 
 // Create 创建地理围栏
@@ -33,14 +49,44 @@ func (s *GeofenceService) Create(ctx context.Context, userID int64, req *dto.Geo
 	g := &model.Geofence{
 		UserID:        userID,
 		Name:          req.Name,
-		RadiusMeters:  req.RadiusMeters,
 		NotifyOnEnter: req.NotifyOnEnter,
 		NotifyOnExit:  req.NotifyOnExit,
 		IsActive:      true,
 	}
-	g.SetCenter(req.CenterLon, req.CenterLat)
 
-	return s.repo.Create(ctx, g)
+	shape := model.GeofenceShape(req.ShapeType)
+	if shape == "" {
+		shape = model.GeofenceShapeCircle
+		if req.WKT != "" || len(req.GeoJSON) > 0 {
+			shape = model.GeofenceShapePolygon
+		}
+	}
+
+	switch shape {
+	case model.GeofenceShapeCircle:
+		if req.RadiusMeters <= 0 || !geo.ValidCoordinate(req.CenterLon, req.CenterLat) {
+			return common.InvalidGeofenceErr
+		}
+		g.SetCircle(req.CenterLon, req.CenterLat, req.RadiusMeters)
+	case model.GeofenceShapePolygon, model.GeofenceShapeMultiPolygon:
+		mp, err := parseGeometry(req.WKT, req.GeoJSON)
+		if err != nil {
+			return err
+		}
+		if shape == model.GeofenceShapePolygon && len(mp) > 1 {
+			return common.InvalidGeofenceErr.WithMsg("polygon geofence has multiple polygons")
+		}
+		if err := g.SetPolygons(mp); err != nil {
+			return common.InvalidGeofenceErr.WithErr(err)
+		}
+	default:
+		return common.InvalidGeofenceErr.WithMsg("unknown shape_type")
+	}
+
+	if err := s.repo.Create(ctx, g); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	return nil
 }
 
 // GetList 获取地理围栏列表
@@ -55,6 +101,7 @@ func (s *GeofenceService) GetList(ctx context.Context, userID int64) ([]*dto.Geo
 		resp[i] = &dto.GeofenceResp{
 			ID:            g.ID,
 			Name:          g.Name,
+			ShapeType:     string(g.ShapeType),
 			CenterLon:     g.CenterLon,
 			CenterLat:     g.CenterLat,
 			RadiusMeters:  g.RadiusMeters,
@@ -64,6 +111,9 @@ func (s *GeofenceService) GetList(ctx context.Context, userID int64) ([]*dto.Geo
 			CreatedAt:     g.CreatedAt,
 			UpdatedAt:     g.UpdatedAt,
 		}
+		if g.IsPolygon() && g.Geometry != nil {
+			resp[i].Geometry = json.RawMessage(*g.Geometry)
+		}
 	}
 
 	return resp, nil
@@ -85,8 +135,25 @@ func (s *GeofenceService) Update(ctx context.Context, userID int64, geofenceID i
 	if req.Name != "" {
 		g.Name = req.Name
 	}
-	if req.RadiusMeters > 0 {
+	resetState := false
+	if req.WKT != "" || len(req.GeoJSON) > 0 {
+		if !g.IsPolygon() {
+			return common.InvalidGeofenceErr.WithMsg("circle geofence cannot take geometry")
+		}
+		mp, err := parseGeometry(req.WKT, req.GeoJSON)
+		if err != nil {
+			return err
+		}
+		if g.ShapeType == model.GeofenceShapePolygon && len(mp) > 1 {
+			return common.InvalidGeofenceErr.WithMsg("polygon geofence has multiple polygons")
+		}
+		if err := g.SetPolygons(mp); err != nil {
+			return common.InvalidGeofenceErr.WithErr(err)
+		}
+		resetState = true
+	} else if req.RadiusMeters > 0 && req.RadiusMeters != g.RadiusMeters && !g.IsPolygon() {
 		g.RadiusMeters = req.RadiusMeters
+		resetState = true
 	}
 	if req.NotifyOnEnter != nil {
 		g.NotifyOnEnter = *req.NotifyOnEnter
@@ -95,10 +162,21 @@ func (s *GeofenceService) Update(ctx context.Context, userID int64, geofenceID i
 		g.NotifyOnExit = *req.NotifyOnExit
 	}
 	if req.IsActive != nil {
+		if g.IsActive != *req.IsActive {
+			resetState = true
+		}
 		g.IsActive = *req.IsActive
 	}
 
-	return s.repo.Update(ctx, g)
+	if err := s.repo.Update(ctx, g); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+
+	// 围栏范围或启用状态变化后，重新建立内外状态基线
+	if resetState {
+		s.clearState(geofenceID)
+	}
+	return nil
 }
 
 // Delete 删除地理围栏
@@ -114,12 +192,128 @@ func (s *GeofenceService) Delete(ctx context.Context, userID int64, geofenceID i
 		return common.PermissionErr
 	}
 
-	return s.repo.Delete(ctx, geofenceID)
+	if err := s.repo.Delete(ctx, geofenceID); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	s.clearState(geofenceID)
+	return nil
 }
 
 // CheckGeofenceEvents 检查围栏事件
-func (s *GeofenceService) CheckGeofenceEvents(ctx context.Context, lon, lat float64, entityType string, entityID int64) error {
-	// TODO: 实现围栏事件检测逻辑
-	// 需要记录上次位置，判断是进入还是离开
-	return nil
+// 对 ownerID 名下的围栏，根据实体在各围栏内外的上次状态判断进入/离开，并按围栏的通知设置记录事件。
+// 实体为用户本人或其绑定的设备；首次出现在某围栏的实体只建立状态基线，不产生事件。
+func (s *GeofenceService) CheckGeofenceEvents(ctx context.Context, ownerID int64, lon, lat, accuracy float64, entityType, entityID string) ([]*model.GeofenceEvent, error) {
+	if accuracy > maxEvaluableAccuracy {
+		return nil, nil
+	}
+
+	geofences, err := s.repo.GetActiveGeofences(ctx, ownerID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if len(geofences) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(geofences))
+	for i, g := range geofences {
+		ids[i] = g.ID
+	}
+	states, err := s.cache.GetStates(ids, entityType, entityID)
+	if err != nil {
+		return nil, common.RedisErr.WithErr(err)
+	}
+
+	var events []*model.GeofenceEvent
+	for _, g := range geofences {
+		wasInside, known := states[g.ID]
+		inside := isInside(g, lon, lat, accuracy, wasInside)
+		if known && inside == wasInside {
+			continue
+		}
+
+		if err := s.cache.SetState(g.ID, entityType, entityID, inside); err != nil {
+			return events, common.RedisErr.WithErr(err)
+		}
+		if !known {
+			continue
+		}
+
+		eventType := model.GeofenceEventExit
+		notify := g.NotifyOnExit
+		if inside {
+			eventType = model.GeofenceEventEnter
+			notify = g.NotifyOnEnter
+		}
+		if !notify {
+			continue
+		}
+
+		event := &model.GeofenceEvent{
+			GeofenceID: g.ID,
+			EntityType: entityType,
+			EntityID:   entityID,
+			EventType:  eventType,
+		}
+		event.SetLocation(lon, lat)
+		if err := s.repo.CreateEvent(ctx, event); err != nil {
+			return events, common.DatabaseErr.WithErr(err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// isInside 带滞回的内外判定：已在围栏内时需越过边界外缓冲区才算离开，
+// 在围栏外时需进入边界内缓冲区才算进入
+func isInside(g *model.Geofence, lon, lat, accuracy float64, wasInside bool) bool {
+	buffer := math.Min(math.Max(accuracy, minHysteresisMeters), maxHysteresisMeters)
+
+	// 有向距离：围栏内为负，围栏外为正；innerDepth 限制进入时需深入的距离，避免小围栏无法触发
+	var signed, innerDepth float64
+	if g.IsPolygon() {
+		if len(g.Polygons) == 0 {
+			return false
+		}
+		signed = g.Polygons.SignedDistance(geo.Point{Lon: lon, Lat: lat})
+		innerDepth = math.Min(buffer, minHysteresisMeters)
+	} else {
+		signed = geo.Distance(g.CenterLon, g.CenterLat, lon, lat) - g.RadiusMeters
+		innerDepth = math.Min(buffer, g.RadiusMeters/2)
+	}
+
+	if wasInside {
+		return signed <= buffer
+	}
+	return signed < -innerDepth
+}
+
+// parseGeometry 解析 WKT 或 GeoJSON 格式的多边形
+func parseGeometry(wkt string, geoJSON json.RawMessage) (geo.MultiPolygon, error) {
+	var (
+		mp  geo.MultiPolygon
+		err error
+	)
+	switch {
+	case wkt != "" && len(geoJSON) > 0:
+		return nil, common.InvalidGeofenceErr.WithMsg("wkt and geojson are mutually exclusive")
+	case wkt != "":
+		mp, err = geo.ParseWKT(wkt)
+	case len(geoJSON) > 0:
+		mp, err = geo.ParseGeoJSON(geoJSON)
+	default:
+		return nil, common.InvalidGeofenceErr.WithMsg("geometry required")
+	}
+	if err != nil {
+		return nil, common.InvalidGeofenceErr.WithErr(err)
+	}
+	return mp, nil
+}
+
+// clearState 清除围栏状态，失败不影响业务
+func (s *GeofenceService) clearState(geofenceID int64) {
+	if err := s.cache.DeleteStates(geofenceID); err != nil {
+		logger.Warn("clear geofence state failed", zap.Int64("geofence_id", geofenceID), zap.Error(err))
+	}
 }
@@ -0,0 +1,139 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"app/adaptor/repo/model"
+	"app/common"
+	"app/service/dto"
+	"app/service/websocket"
+	"app/utils/logger"
+)
+
+// staleDeviceFixAge 定位时间早于该时长的设备位置视为离线补传
+const staleDeviceFixAge = 5 * time.Minute
+
+// ReportDeviceLocation 上报设备位置，设备必须绑定在调用者名下
+// 校验规则同用户位置上报；通过后写入设备位置、更新缓存与设备状态（视同一次心跳），并以设备身份判定绑定者的围栏
+func (s *LocationService) ReportDeviceLocation(ctx context.Context, userID int64, req *dto.LocationReportReq) error {
+	ownerID, err := s.deviceRepo.GetUserByDevice(ctx, req.DeviceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return common.DeviceNotFoundErr
+	}
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if ownerID != userID {
+		return common.PermissionErr
+	}
+	return s.reportDeviceLocation(ctx, ownerID, req)
+}
+
+// ReportAuthedDeviceLocation 上报通过设备密钥鉴权的设备自身位置
+// 请求中的 device_id 可省略，填写时必须与鉴权设备一致
+func (s *LocationService) ReportAuthedDeviceLocation(ctx context.Context, device *common.Device, req *dto.LocationReportReq) error {
+	if req.DeviceID != "" && req.DeviceID != device.DeviceID {
+		return common.PermissionErr
+	}
+	req.DeviceID = device.DeviceID
+	return s.reportDeviceLocation(ctx, device.UserID, req)
+}
+
+// reportDeviceLocation 保存已确认绑定关系的设备位置
+// 离线补传（定位时间早于 staleDeviceFixAge）或乱序到达的点只写入历史并刷新设备状态，
+// 不更新设备当前位置，也不触发围栏判定与推送
+func (s *LocationService) reportDeviceLocation(ctx context.Context, ownerID int64, req *dto.LocationReportReq) error {
+	now := time.Now()
+	if reason, detail := validateRecordedAt(req.RecordedAt, now); reason != "" {
+		s.recordRejections(ctx, []*model.RejectedLocation{newRejectedLocation(ownerID, req, reason, detail)})
+		return rejectErr(reason)
+	}
+	recordedAt := req.RecordedAt
+	if recordedAt.IsZero() {
+		recordedAt = now
+	}
+
+	prev := s.lastDeviceFix(ctx, req.DeviceID)
+	cur := &fix{lon: req.Longitude, lat: req.Latitude, accuracy: req.Accuracy, at: recordedAt}
+	if reason, detail := validateFix(cur, prev); reason != "" {
+		s.recordRejections(ctx, []*model.RejectedLocation{newRejectedLocation(ownerID, req, reason, detail)})
+		return rejectErr(reason)
+	}
+
+	loc := &model.DeviceLocation{
+		DeviceID:         req.DeviceID,
+		Accuracy:         req.Accuracy,
+		BatteryLevel:     req.BatteryLevel,
+		ConnectionStatus: model.DeviceConnectionOnline,
+		RecordedAt:       recordedAt,
+	}
+	loc.SetLocation(req.Longitude, req.Latitude)
+	if err := s.repo.CreateDeviceLocation(ctx, loc); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+
+	// 电量为 0 视为未上报，不覆盖设备电量
+	var battery *int
+	if req.BatteryLevel > 0 {
+		battery = &req.BatteryLevel
+	}
+	if err := s.devices.Touch(ctx, req.DeviceID, battery, model.DeviceStatusReasonReport); err != nil {
+		logger.Warn("update device status failed", zap.String("device_id", req.DeviceID), zap.Error(err))
+	}
+
+	if !isLiveFix(recordedAt, prev, now) {
+		return nil
+	}
+
+	resp := toDeviceLocationResp(loc)
+	if err := s.cache.SetDeviceLocation(req.DeviceID, resp); err != nil {
+		logger.Warn("cache device location failed", zap.String("device_id", req.DeviceID), zap.Error(err))
+	}
+
+	if _, err := s.geofence.CheckGeofenceEvents(ctx, ownerID, loc.Longitude, loc.Latitude, loc.Accuracy, model.GeofenceEntityDevice, req.DeviceID); err != nil {
+		logger.Warn("check geofence events failed", zap.String("device_id", req.DeviceID), zap.Error(err))
+	}
+
+	// 设备位置仅绑定者可见
+	s.hub.PushLocation(websocket.EntityDevice, req.DeviceID, resp, []int64{ownerID})
+	return nil
+}
+
+// isLiveFix 判断定位点能否作为设备当前位置：不早于上一个点，且不是离线补传的点
+func isLiveFix(at time.Time, prev *fix, now time.Time) bool {
+	if prev != nil && at.Before(prev.at) {
+		return false
+	}
+	return now.Sub(at) <= staleDeviceFixAge
+}
+
+// lastDeviceFix 获取设备上一个已接受的定位点，没有或获取失败时返回空
+func (s *LocationService) lastDeviceFix(ctx context.Context, deviceID string) *fix {
+	loc, err := s.getLatestDeviceLocation(ctx, deviceID)
+	if err != nil {
+		return nil
+	}
+	at := loc.RecordedAt
+	if at.IsZero() {
+		at = loc.CreatedAt
+	}
+	return &fix{lon: loc.Longitude, lat: loc.Latitude, accuracy: loc.Accuracy, at: at}
+}
+
+func toDeviceLocationResp(loc *model.DeviceLocation) *dto.LocationResp {
+	return &dto.LocationResp{
+		ID:           loc.ID,
+		DeviceID:     loc.DeviceID,
+		Longitude:    loc.Longitude,
+		Latitude:     loc.Latitude,
+		Accuracy:     loc.Accuracy,
+		BatteryLevel: loc.BatteryLevel,
+		RecordedAt:   loc.RecordedAt,
+		CreatedAt:    loc.CreatedAt,
+	}
+}
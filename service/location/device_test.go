@@ -0,0 +1,33 @@
+package location
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsLiveFix(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	prev := &fix{at: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		prev *fix
+		want bool
+	}{
+		{"first fix", now, nil, true},
+		{"newer than previous", now, prev, true},
+		{"same time as previous", prev.at, prev, true},
+		{"older than previous", prev.at.Add(-time.Second), prev, false},
+		{"within stale age", now.Add(-staleDeviceFixAge), nil, true},
+		{"backfilled", now.Add(-staleDeviceFixAge - time.Second), nil, false},
+		{"backfilled after old previous", now.Add(-time.Hour), &fix{at: now.Add(-2 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLiveFix(tt.at, tt.prev, now); got != tt.want {
+				t.Fatalf("isLiveFix = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
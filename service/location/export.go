@@ -0,0 +1,180 @@
+package location
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/adaptor/repo/model"
+	"app/common"
+	"app/service/dto"
+)
+
+const (
+	// 单次导出的最大时间跨度
+	maxExportRange = 31 * 24 * time.Hour
+
+	// KML 每个 gx:Track 缓冲的点数，超过后输出为 gx:MultiTrack 中的下一段
+	kmlTrackChunk = 500
+)
+
+// trackWriter 轨迹文件写入器
+type trackWriter interface {
+	begin(name string) error
+	point(loc *model.UserLocation) error
+	end() error
+}
+
+// CheckTrackExport 校验导出参数，应在开始写入响应前调用
+func (s *LocationService) CheckTrackExport(req *dto.TrackExportReq) error {
+	if !req.EndTime.After(req.StartTime) || req.EndTime.Sub(req.StartTime) > maxExportRange {
+		return common.ParamErr.WithMsg("invalid time range")
+	}
+	switch req.Format {
+	case "", dto.FormatGPX, dto.FormatKML:
+		return nil
+	default:
+		return common.ParamErr.WithMsg("unsupported format")
+	}
+}
+
+// ExportTrack 将用户时间段内的位置流式导出为 GPX 1.1 或 KML
+// 逐行读取数据库并写入 w，写入开始后出错只能中断输出
+func (s *LocationService) ExportTrack(ctx context.Context, userID int64, req *dto.TrackExportReq, w io.Writer) error {
+	if err := s.CheckTrackExport(req); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var tw trackWriter
+	if req.Format == dto.FormatKML {
+		tw = &kmlWriter{w: bw}
+	} else {
+		tw = &gpxWriter{w: bw}
+	}
+
+	name := fmt.Sprintf("%s - %s", req.StartTime.UTC().Format(time.RFC3339), req.EndTime.UTC().Format(time.RFC3339))
+	if err := tw.begin(name); err != nil {
+		return err
+	}
+	if err := s.repo.StreamUserLocations(ctx, userID, req.StartTime, req.EndTime, tw.point); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if err := tw.end(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ExportFilename 导出文件名
+func ExportFilename(req *dto.TrackExportReq) string {
+	ext := dto.FormatGPX
+	if req.Format == dto.FormatKML {
+		ext = dto.FormatKML
+	}
+	return fmt.Sprintf("track_%s_%s.%s", req.StartTime.UTC().Format("20060102"), req.EndTime.UTC().Format("20060102"), ext)
+}
+
+// ExportContentType 导出文件的 Content-Type
+func ExportContentType(req *dto.TrackExportReq) string {
+	if req.Format == dto.FormatKML {
+		return "application/vnd.google-earth.kml+xml"
+	}
+	return "application/gpx+xml"
+}
+
+// gpxWriter GPX 1.1，速度写入 Garmin TrackPointExtension
+type gpxWriter struct {
+	w *bufio.Writer
+}
+
+func (g *gpxWriter) begin(name string) error {
+	_, err := fmt.Fprintf(g.w, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="location-sharing" xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2">
+<trk><name>%s</name><trkseg>
+`, escapeXML(name))
+	return err
+}
+
+func (g *gpxWriter) point(loc *model.UserLocation) error {
+	_, err := fmt.Fprintf(g.w,
+		`<trkpt lat="%s" lon="%s"><ele>%s</ele><time>%s</time><extensions><gpxtpx:TrackPointExtension><gpxtpx:speed>%s</gpxtpx:speed></gpxtpx:TrackPointExtension></extensions></trkpt>
+`,
+		formatCoord(loc.Latitude), formatCoord(loc.Longitude), formatCoord(loc.Altitude),
+		loc.RecordedAt.UTC().Format(time.RFC3339), formatCoord(loc.Speed))
+	return err
+}
+
+func (g *gpxWriter) end() error {
+	_, err := g.w.WriteString("</trkseg></trk>\n</gpx>\n")
+	return err
+}
+
+// kmlWriter KML 2.2，使用 gx:MultiTrack 分段输出，每段缓冲 kmlTrackChunk 个点
+// gx:Track 要求时间、坐标分组排列，分段后只需缓冲一段的数据
+type kmlWriter struct {
+	w     *bufio.Writer
+	chunk []*model.UserLocation
+}
+
+func (k *kmlWriter) begin(name string) error {
+	_, err := fmt.Fprintf(k.w, `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document><name>%s</name>
+<Schema id="trackSchema"><gx:SimpleArrayField name="speed" type="float"><displayName>Speed (m/s)</displayName></gx:SimpleArrayField></Schema>
+<Placemark><name>%s</name><gx:MultiTrack><altitudeMode>absolute</altitudeMode><gx:interpolate>1</gx:interpolate>
+`, escapeXML(name), escapeXML(name))
+	return err
+}
+
+func (k *kmlWriter) point(loc *model.UserLocation) error {
+	k.chunk = append(k.chunk, loc)
+	if len(k.chunk) >= kmlTrackChunk {
+		return k.flush()
+	}
+	return nil
+}
+
+func (k *kmlWriter) flush() error {
+	if len(k.chunk) == 0 {
+		return nil
+	}
+	k.w.WriteString("<gx:Track>\n")
+	for _, loc := range k.chunk {
+		fmt.Fprintf(k.w, "<when>%s</when>\n", loc.RecordedAt.UTC().Format(time.RFC3339))
+	}
+	for _, loc := range k.chunk {
+		fmt.Fprintf(k.w, "<gx:coord>%s %s %s</gx:coord>\n",
+			formatCoord(loc.Longitude), formatCoord(loc.Latitude), formatCoord(loc.Altitude))
+	}
+	k.w.WriteString(`<ExtendedData><SchemaData schemaUrl="#trackSchema"><gx:SimpleArrayData name="speed">` + "\n")
+	for _, loc := range k.chunk {
+		fmt.Fprintf(k.w, "<gx:value>%s</gx:value>\n", formatCoord(loc.Speed))
+	}
+	_, err := k.w.WriteString("</gx:SimpleArrayData></SchemaData></ExtendedData>\n</gx:Track>\n")
+	k.chunk = k.chunk[:0]
+	return err
+}
+
+func (k *kmlWriter) end() error {
+	if err := k.flush(); err != nil {
+		return err
+	}
+	_, err := k.w.WriteString("</gx:MultiTrack></Placemark>\n</Document>\n</kml>\n")
+	return err
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package location
+
+import (
+	"app/common"
+	"app/service/dto"
+	"app/utils/geo"
+)
+
+// 编码折线允许的最大精度，7 位小数约为 1 厘米
+const maxPolylinePrecision = 7
+
+// FormatHistory 按输出格式转换位置历史
+// 位置历史按时间倒序返回，geojson / polyline 格式转换为时间正序以便直接绘制轨迹
+func FormatHistory(locs []*dto.LocationResp, format string, precision int) (interface{}, error) {
+	if err := checkFormat(format, precision); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case dto.FormatGeoJSON:
+		ordered := chronological(locs)
+		coords := make([][]float64, len(ordered))
+		features := make([]*dto.GeoJSONFeature, 0, len(ordered)+1)
+		features = append(features, nil)
+		for i, loc := range ordered {
+			coords[i] = []float64{loc.Longitude, loc.Latitude}
+			features = append(features, pointFeature(loc.Longitude, loc.Latitude, map[string]interface{}{
+				"id":            loc.ID,
+				"accuracy":      loc.Accuracy,
+				"altitude":      loc.Altitude,
+				"speed":         loc.Speed,
+				"bearing":       loc.Bearing,
+				"battery_level": loc.BatteryLevel,
+				"location_mode": loc.LocationMode,
+				"timestamp":     loc.RecordedAt,
+			}))
+		}
+		features[0] = lineFeature(coords, map[string]interface{}{"count": len(ordered)})
+		return &dto.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: features}, nil
+
+	case dto.FormatPolyline:
+		ordered := chronological(locs)
+		points := make([]geo.Point, len(ordered))
+		timestamps := make([]int64, len(ordered))
+		for i, loc := range ordered {
+			points[i] = geo.Point{Lon: loc.Longitude, Lat: loc.Latitude}
+			timestamps[i] = loc.RecordedAt.Unix()
+		}
+		return encodedPolyline(points, timestamps, precision), nil
+
+	default:
+		return locs, nil
+	}
+}
+
+// FormatTrajectory 按输出格式转换轨迹
+func FormatTrajectory(traj *dto.TrajectoryResp, format string, precision int) (interface{}, error) {
+	if err := checkFormat(format, precision); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case dto.FormatGeoJSON:
+		coords := make([][]float64, len(traj.Points))
+		features := make([]*dto.GeoJSONFeature, 0, len(traj.Points)+1)
+		features = append(features, nil)
+		for i, p := range traj.Points {
+			coords[i] = []float64{p.Longitude, p.Latitude}
+			features = append(features, pointFeature(p.Longitude, p.Latitude, map[string]interface{}{
+				"timestamp": p.Timestamp,
+			}))
+		}
+		features[0] = lineFeature(coords, map[string]interface{}{
+			"distance":       traj.Distance,
+			"duration":       traj.Duration,
+			"original_count": traj.OriginalCount,
+		})
+		return &dto.GeoJSONFeatureCollection{Type: "FeatureCollection", Features: features}, nil
+
+	case dto.FormatPolyline:
+		points := make([]geo.Point, len(traj.Points))
+		timestamps := make([]int64, len(traj.Points))
+		for i, p := range traj.Points {
+			points[i] = geo.Point{Lon: p.Longitude, Lat: p.Latitude}
+			timestamps[i] = p.Timestamp.Unix()
+		}
+		resp := encodedPolyline(points, timestamps, precision)
+		resp.Distance = &traj.Distance
+		resp.Duration = &traj.Duration
+		return resp, nil
+
+	default:
+		return traj, nil
+	}
+}
+
+// checkFormat 校验输出格式与编码精度，precision 为 0 时使用默认精度
+func checkFormat(format string, precision int) error {
+	switch format {
+	case "", dto.FormatJSON, dto.FormatGeoJSON, dto.FormatPolyline:
+	default:
+		return common.ParamErr.WithMsg("unsupported format")
+	}
+	if precision < 0 || precision > maxPolylinePrecision {
+		return common.ParamErr.WithMsg("invalid precision")
+	}
+	return nil
+}
+
+func encodedPolyline(points []geo.Point, timestamps []int64, precision int) *dto.EncodedPolylineResp {
+	if precision == 0 {
+		precision = geo.DefaultPolylinePrecision
+	}
+	return &dto.EncodedPolylineResp{
+		Polyline:   geo.EncodePolyline(points, precision),
+		Precision:  precision,
+		Timestamps: timestamps,
+	}
+}
+
+// lineFeature 轨迹线要素；GeoJSON 要求 LineString 至少两个点，不足时几何为空
+func lineFeature(coords [][]float64, props map[string]interface{}) *dto.GeoJSONFeature {
+	feature := &dto.GeoJSONFeature{Type: "Feature", Properties: props}
+	if len(coords) >= 2 {
+		feature.Geometry = &dto.GeoJSONGeometry{Type: "LineString", Coordinates: coords}
+	}
+	return feature
+}
+
+func pointFeature(lon, lat float64, props map[string]interface{}) *dto.GeoJSONFeature {
+	return &dto.GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   &dto.GeoJSONGeometry{Type: "Point", Coordinates: []float64{lon, lat}},
+		Properties: props,
+	}
+}
+
+// chronological 返回按时间正序排列的副本
+func chronological(locs []*dto.LocationResp) []*dto.LocationResp {
+	ordered := make([]*dto.LocationResp, len(locs))
+	for i, loc := range locs {
+		ordered[len(locs)-1-i] = loc
+	}
+	return ordered
+}
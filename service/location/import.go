@@ -0,0 +1,293 @@
+package location
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"app/adaptor/repo/model"
+	"app/common"
+	"app/service/dto"
+)
+
+const (
+	// 单次导入的最大点数
+	maxImportPoints = 100000
+)
+
+// importedPoint 从文件中解析出的位置点，at 为零值表示文件中没有时间
+type importedPoint struct {
+	lon float64
+	lat float64
+	ele float64
+	at  time.Time
+}
+
+// ImportLocations 从 GPX 或 GeoJSON 文件导入历史位置
+// 位置点按时间排序后逐点校验（规则同批量上报，相邻点之间做速度校验），
+// 与已有记录同一秒的点视为重复跳过。导入的点只补充历史，不更新最新位置、不触发围栏与推送，
+// 但会参与停留检测
+func (s *LocationService) ImportLocations(ctx context.Context, userID int64, format string, r io.Reader) (*dto.LocationImportResp, error) {
+	var (
+		points []importedPoint
+		err    error
+	)
+	switch format {
+	case dto.FormatGPX:
+		points, err = parseGPX(r)
+	case dto.FormatGeoJSON:
+		points, err = parseGeoJSONTrack(r)
+	default:
+		return nil, common.ParamErr.WithMsg("unsupported format")
+	}
+	if err != nil {
+		return nil, common.ParamErr.WithErr(err)
+	}
+	if len(points) > maxImportPoints {
+		return nil, common.ParamErr.WithMsg(fmt.Sprintf("too many points, max %d", maxImportPoints))
+	}
+
+	resp := &dto.LocationImportResp{Total: len(points), Reasons: map[string]int{}}
+	var rejected []*model.RejectedLocation
+	reject := func(p importedPoint, reason model.RejectReason, detail string) {
+		resp.Rejected++
+		resp.Reasons[string(reason)]++
+		rejected = append(rejected, &model.RejectedLocation{
+			UserID:    userID,
+			Longitude: p.lon,
+			Latitude:  p.lat,
+			Reason:    reason,
+			Detail:    detail,
+		})
+	}
+
+	latest := time.Now().Add(maxClockSkew)
+	timed := make([]importedPoint, 0, len(points))
+	for _, p := range points {
+		if p.at.IsZero() || p.at.After(latest) {
+			reject(p, model.RejectReasonInvalidTimestamp, "")
+			continue
+		}
+		timed = append(timed, p)
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].at.Before(timed[j].at) })
+
+	seen := make(map[int64]bool)
+	if len(timed) > 0 {
+		// 查询范围覆盖首尾两点所在的整秒
+		existing, err := s.repo.GetUserLocationTimes(ctx, userID,
+			timed[0].at.Truncate(time.Second), timed[len(timed)-1].at.Truncate(time.Second).Add(time.Second))
+		if err != nil {
+			return nil, common.DatabaseErr.WithErr(err)
+		}
+		for _, t := range existing {
+			seen[t.Unix()] = true
+		}
+	}
+
+	var (
+		prev *fix
+		locs []*model.UserLocation
+	)
+	for _, p := range timed {
+		sec := p.at.Unix()
+		if seen[sec] {
+			resp.Duplicates++
+			continue
+		}
+		cur := &fix{lon: p.lon, lat: p.lat, at: p.at}
+		if reason, detail := validateFix(cur, prev); reason != "" {
+			reject(p, reason, detail)
+			continue
+		}
+		seen[sec] = true
+		prev = cur
+
+		loc := &model.UserLocation{
+			UserID:       userID,
+			Altitude:     p.ele,
+			LocationMode: model.LocationModeImport,
+			RecordedAt:   p.at,
+		}
+		loc.SetLocation(p.lon, p.lat)
+		locs = append(locs, loc)
+	}
+
+	created, err := s.repo.BatchCreateUserLocations(ctx, locs)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if len(created) > 0 {
+		s.visits.Notify(userID, created[0].RecordedAt)
+	}
+	s.recordRejections(ctx, rejected)
+	resp.Accepted = len(created)
+	resp.Duplicates += len(locs) - len(created)
+	return resp, nil
+}
+
+type gpxDocument struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat  float64 `xml:"lat,attr"`
+				Lon  float64 `xml:"lon,attr"`
+				Ele  float64 `xml:"ele"`
+				Time string  `xml:"time"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// parseGPX 解析 GPX 中所有轨迹段的 trkpt
+func parseGPX(r io.Reader) ([]importedPoint, error) {
+	var doc gpxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	var points []importedPoint
+	for _, trk := range doc.Tracks {
+		for _, seg := range trk.Segments {
+			for _, pt := range seg.Points {
+				points = append(points, importedPoint{lon: pt.Lon, lat: pt.Lat, ele: pt.Ele, at: parseImportTime(pt.Time)})
+			}
+		}
+	}
+	return points, nil
+}
+
+type geoJSONTrackObject struct {
+	Type        string            `json:"type"`
+	Features    []json.RawMessage `json:"features"`
+	Geometry    json.RawMessage   `json:"geometry"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Properties  struct {
+		CoordTimes json.RawMessage `json:"coordTimes"`
+	} `json:"properties"`
+}
+
+// parseGeoJSONTrack 解析 LineString / MultiLineString，可包裹在 Feature 或 FeatureCollection 中
+// 各点时间取自 Feature 的 properties.coordTimes（与坐标一一对应的 RFC3339 时间），
+// 缺少 coordTimes 或数量与坐标不一致时整个文件拒绝
+func parseGeoJSONTrack(r io.Reader) ([]importedPoint, error) {
+	var obj geoJSONTrackObject
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, err
+	}
+	return geoJSONTrackPoints(&obj, nil)
+}
+
+func geoJSONTrackPoints(obj *geoJSONTrackObject, coordTimes json.RawMessage) ([]importedPoint, error) {
+	switch obj.Type {
+	case "FeatureCollection":
+		var points []importedPoint
+		for _, raw := range obj.Features {
+			var feature geoJSONTrackObject
+			if err := json.Unmarshal(raw, &feature); err != nil {
+				return nil, err
+			}
+			pts, err := geoJSONTrackPoints(&feature, nil)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, pts...)
+		}
+		return points, nil
+
+	case "Feature":
+		if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+			return nil, nil
+		}
+		var geometry geoJSONTrackObject
+		if err := json.Unmarshal(obj.Geometry, &geometry); err != nil {
+			return nil, err
+		}
+		return geoJSONTrackPoints(&geometry, obj.Properties.CoordTimes)
+
+	case "LineString":
+		var coords [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		var times []string
+		if err := parseCoordTimes(coordTimes, &times); err != nil {
+			return nil, err
+		}
+		return lineStringPoints(coords, times)
+
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &lines); err != nil {
+			return nil, err
+		}
+		var times [][]string
+		if err := parseCoordTimes(coordTimes, &times); err != nil {
+			return nil, err
+		}
+		if len(times) != len(lines) {
+			return nil, errCoordTimesMismatch
+		}
+		var points []importedPoint
+		for i, coords := range lines {
+			pts, err := lineStringPoints(coords, times[i])
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, pts...)
+		}
+		return points, nil
+
+	default:
+		// 其他几何类型（如航点 Point）不作为轨迹导入
+		return nil, nil
+	}
+}
+
+var (
+	errMissingCoordTimes  = errors.New("geojson: track has no properties.coordTimes, point times are required")
+	errCoordTimesMismatch = errors.New("geojson: properties.coordTimes does not match coordinates")
+)
+
+// parseCoordTimes 解析 coordTimes，缺少或格式不符时返回错误
+func parseCoordTimes(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return errMissingCoordTimes
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errCoordTimesMismatch
+	}
+	return nil
+}
+
+// lineStringPoints 按下标对应坐标与时间，times 数量必须与坐标一致
+func lineStringPoints(coords [][]float64, times []string) ([]importedPoint, error) {
+	if len(times) != len(coords) {
+		return nil, errCoordTimesMismatch
+	}
+	points := make([]importedPoint, 0, len(coords))
+	for i, c := range coords {
+		if len(c) < 2 {
+			return nil, errors.New("geojson: position must have at least 2 values")
+		}
+		p := importedPoint{lon: c[0], lat: c[1]}
+		if len(c) > 2 {
+			p.ele = c[2]
+		}
+		p.at = parseImportTime(times[i])
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func parseImportTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,113 @@
+package location
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGeoJSONTrack(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		json  string
+		times []time.Time
+	}{
+		{
+			"feature",
+			`{"type":"Feature","properties":{"coordTimes":["2024-01-01T08:00:00Z","2024-01-01T08:00:01Z"]},
+			  "geometry":{"type":"LineString","coordinates":[[116.39,39.9,50],[116.391,39.9]]}}`,
+			[]time.Time{t0, t0.Add(time.Second)},
+		},
+		{
+			"multilinestring",
+			`{"type":"Feature","properties":{"coordTimes":[["2024-01-01T08:00:00Z"],["2024-01-01T08:00:02Z","bad"]]},
+			  "geometry":{"type":"MultiLineString","coordinates":[[[116.39,39.9]],[[116.391,39.9],[116.392,39.9]]]}}`,
+			[]time.Time{t0, t0.Add(2 * time.Second), {}},
+		},
+		{
+			"feature collection with waypoint",
+			`{"type":"FeatureCollection","features":[
+			  {"type":"Feature","properties":{"time":"2024-01-01T07:00:00Z"},"geometry":{"type":"Point","coordinates":[116.38,39.9]}},
+			  {"type":"Feature","properties":{"coordTimes":["2024-01-01T08:00:00Z"]},"geometry":{"type":"LineString","coordinates":[[116.39,39.9]]}},
+			  {"type":"Feature","properties":{},"geometry":null}]}`,
+			[]time.Time{t0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := parseGeoJSONTrack(strings.NewReader(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(points) != len(tt.times) {
+				t.Fatalf("points = %d, want %d", len(points), len(tt.times))
+			}
+			for i, p := range points {
+				if !p.at.Equal(tt.times[i]) {
+					t.Fatalf("point %d at %v, want %v", i, p.at, tt.times[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONTrackInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want error
+	}{
+		{
+			"linestring without coordTimes",
+			`{"type":"Feature","properties":{"time":"2024-01-01T08:00:00Z"},"geometry":{"type":"LineString","coordinates":[[116.39,39.9],[116.391,39.9]]}}`,
+			errMissingCoordTimes,
+		},
+		{
+			"bare linestring",
+			`{"type":"LineString","coordinates":[[116.39,39.9],[116.391,39.9]]}`,
+			errMissingCoordTimes,
+		},
+		{
+			"null coordTimes",
+			`{"type":"Feature","properties":{"coordTimes":null},"geometry":{"type":"LineString","coordinates":[[116.39,39.9]]}}`,
+			errMissingCoordTimes,
+		},
+		{
+			"coordTimes shorter than coordinates",
+			`{"type":"Feature","properties":{"coordTimes":["2024-01-01T08:00:00Z"]},"geometry":{"type":"LineString","coordinates":[[116.39,39.9],[116.391,39.9]]}}`,
+			errCoordTimesMismatch,
+		},
+		{
+			"coordTimes not strings",
+			`{"type":"Feature","properties":{"coordTimes":[1704096000]},"geometry":{"type":"LineString","coordinates":[[116.39,39.9]]}}`,
+			errCoordTimesMismatch,
+		},
+		{
+			"multilinestring with flat coordTimes",
+			`{"type":"Feature","properties":{"coordTimes":["2024-01-01T08:00:00Z"]},"geometry":{"type":"MultiLineString","coordinates":[[[116.39,39.9]]]}}`,
+			errCoordTimesMismatch,
+		},
+		{
+			"multilinestring missing a line",
+			`{"type":"Feature","properties":{"coordTimes":[["2024-01-01T08:00:00Z"]]},"geometry":{"type":"MultiLineString","coordinates":[[[116.39,39.9]],[[116.391,39.9]]]}}`,
+			errCoordTimesMismatch,
+		},
+		{
+			"one feature without times",
+			`{"type":"FeatureCollection","features":[
+			  {"type":"Feature","properties":{"coordTimes":["2024-01-01T08:00:00Z"]},"geometry":{"type":"LineString","coordinates":[[116.39,39.9]]}},
+			  {"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[116.39,39.9]]}}]}`,
+			errMissingCoordTimes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseGeoJSONTrack(strings.NewReader(tt.json)); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
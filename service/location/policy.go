@@ -0,0 +1,128 @@
+package location
+
+import (
+	"context"
+	"strconv"
+
+	"app/adaptor/repo/model"
+	"app/common"
+)
+
+// 位置可见性策略：
+//   - 用户本人始终可见
+//   - 已接受的好友，且对方向查看者的共享状态为 sharing、未开启幽灵模式或关闭位置共享
+//   - 设备位置仅设备绑定者可见
+
+// CheckEntityVisible 校验 requesterID 是否可以查看实体（用户或设备）的位置
+func (s *LocationService) CheckEntityVisible(ctx context.Context, requesterID int64, entityType, entityID string) error {
+	switch entityType {
+	case model.GeofenceEntityUser:
+		targetID, err := strconv.ParseInt(entityID, 10, 64)
+		if err != nil || targetID <= 0 {
+			return common.ParamErr.WithMsg("invalid user id")
+		}
+		return s.checkUserVisible(ctx, requesterID, targetID)
+	case model.GeofenceEntityDevice:
+		return s.checkDeviceVisible(ctx, requesterID, entityID)
+	default:
+		return common.ParamErr.WithMsg("unknown entity type")
+	}
+}
+
+// checkUserVisible 校验 requesterID 是否可以查看 targetID 的位置
+func (s *LocationService) checkUserVisible(ctx context.Context, requesterID, targetID int64) error {
+	if requesterID == 0 {
+		return common.AuthErr
+	}
+	if requesterID == targetID {
+		return nil
+	}
+
+	// 对方的好友记录中保存对方向查看者的共享状态
+	outbound, err := s.friendRepo.GetFriend(ctx, targetID, requesterID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	inbound, err := s.friendRepo.GetFriend(ctx, requesterID, targetID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	settings, err := s.settingsRepo.Get(ctx, targetID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+
+	if !canView(outbound, inbound, settings) {
+		return common.PermissionErr
+	}
+	return nil
+}
+
+// checkDeviceVisible 校验 requesterID 是否可以查看设备位置
+func (s *LocationService) checkDeviceVisible(ctx context.Context, requesterID int64, deviceID string) error {
+	if requesterID == 0 {
+		return common.AuthErr
+	}
+	d, err := s.deviceRepo.Get(ctx, deviceID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if d == nil {
+		return common.DeviceNotFoundErr
+	}
+	if d.UserID != requesterID {
+		return common.PermissionErr
+	}
+	return nil
+}
+
+// viewersOf 获取可以查看该用户位置的用户（包括本人），用于实时推送
+func (s *LocationService) viewersOf(ctx context.Context, userID int64) ([]int64, error) {
+	viewers := []int64{userID}
+
+	settings, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if settings != nil && (settings.GhostMode || !settings.ShareLocation) {
+		return viewers, nil
+	}
+
+	outbound, err := s.friendRepo.GetFriends(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	inbound, err := s.friendRepo.GetFriendsOf(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	accepted := make(map[int64]bool, len(inbound))
+	for _, f := range inbound {
+		accepted[f.UserID] = true
+	}
+	for _, f := range outbound {
+		if accepted[f.FriendID] && isSharing(f.SharingStatus) {
+			viewers = append(viewers, f.FriendID)
+		}
+	}
+	return viewers, nil
+}
+
+// canView 根据双向好友关系和目标用户设置判断是否可见
+// outbound 为目标用户 -> 查看者的关系，inbound 为查看者 -> 目标用户的关系
+func canView(outbound, inbound *model.Friend, settings *model.UserSettings) bool {
+	if outbound == nil || inbound == nil {
+		return false
+	}
+	if outbound.Status != model.FriendStatusAccepted || inbound.Status != model.FriendStatusAccepted {
+		return false
+	}
+	if !isSharing(outbound.SharingStatus) {
+		return false
+	}
+	if settings != nil && (settings.GhostMode || !settings.ShareLocation) {
+		return false
+	}
+	return true
+}
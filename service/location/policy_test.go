@@ -0,0 +1,281 @@
+package location
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"app/adaptor/repo/device"
+	"app/adaptor/repo/friend"
+	"app/adaptor/repo/model"
+	"app/adaptor/repo/settings"
+	"app/common"
+)
+
+// allSharingStatuses 覆盖所有共享状态，空值为旧数据，"expired" 为未知状态（应按不共享处理）
+var allSharingStatuses = []model.SharingStatus{
+	"",
+	model.SharingStatusSharing,
+	model.SharingStatusPaused,
+	model.SharingStatusHidden,
+	"expired",
+}
+
+type fakeFriendRepo struct {
+	friend.IFriendRepository
+	friends []*model.Friend
+}
+
+func (r *fakeFriendRepo) GetFriend(ctx context.Context, userID, friendID int64) (*model.Friend, error) {
+	for _, f := range r.friends {
+		if f.UserID == userID && f.FriendID == friendID {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeFriendRepo) GetFriends(ctx context.Context, userID int64, status string) ([]*model.Friend, error) {
+	var out []*model.Friend
+	for _, f := range r.friends {
+		if f.UserID == userID && string(f.Status) == status {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeFriendRepo) GetFriendsOf(ctx context.Context, friendID int64, status string) ([]*model.Friend, error) {
+	var out []*model.Friend
+	for _, f := range r.friends {
+		if f.FriendID == friendID && string(f.Status) == status {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+type fakeSettingsRepo struct {
+	settings.ISettingsRepository
+	settings map[int64]*model.UserSettings
+}
+
+func (r *fakeSettingsRepo) Get(ctx context.Context, userID int64) (*model.UserSettings, error) {
+	return r.settings[userID], nil
+}
+
+type fakeDeviceRepo struct {
+	device.IDeviceRepository
+	devices map[string]*model.Device
+}
+
+func (r *fakeDeviceRepo) Get(ctx context.Context, deviceID string) (*model.Device, error) {
+	return r.devices[deviceID], nil
+}
+
+func newPolicyService(friends []*model.Friend, userSettings map[int64]*model.UserSettings) *LocationService {
+	return &LocationService{
+		friendRepo:   &fakeFriendRepo{friends: friends},
+		settingsRepo: &fakeSettingsRepo{settings: userSettings},
+		deviceRepo: &fakeDeviceRepo{devices: map[string]*model.Device{
+			"dev-1": {ID: "dev-1", UserID: 1},
+		}},
+	}
+}
+
+func link(userID, friendID int64, status model.FriendStatus, sharing model.SharingStatus) *model.Friend {
+	return &model.Friend{UserID: userID, FriendID: friendID, Status: status, SharingStatus: sharing}
+}
+
+// errCode 取出错误码，nil 返回 0
+func errCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	e, ok := err.(common.Errno)
+	if !ok {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+	return e.Code
+}
+
+func TestCanView(t *testing.T) {
+	accepted := func(sharing model.SharingStatus) *model.Friend {
+		return link(2, 1, model.FriendStatusAccepted, sharing)
+	}
+	inbound := link(1, 2, model.FriendStatusAccepted, model.SharingStatusSharing)
+
+	tests := []struct {
+		name     string
+		outbound *model.Friend
+		inbound  *model.Friend
+		settings *model.UserSettings
+		want     bool
+	}{
+		{"sharing", accepted(model.SharingStatusSharing), inbound, nil, true},
+		{"legacy empty status", accepted(""), inbound, nil, true},
+		{"paused", accepted(model.SharingStatusPaused), inbound, nil, false},
+		{"hidden", accepted(model.SharingStatusHidden), inbound, nil, false},
+		{"unknown status", accepted("expired"), inbound, nil, false},
+		{"no outbound record", nil, inbound, nil, false},
+		{"no inbound record", accepted(model.SharingStatusSharing), nil, nil, false},
+		{"outbound pending", link(2, 1, model.FriendStatusPending, model.SharingStatusSharing), inbound, nil, false},
+		{"inbound rejected", accepted(model.SharingStatusSharing), link(1, 2, model.FriendStatusRejected, model.SharingStatusSharing), nil, false},
+		{"ghost mode", accepted(model.SharingStatusSharing), inbound, &model.UserSettings{GhostMode: true, ShareLocation: true}, false},
+		{"share location off", accepted(model.SharingStatusSharing), inbound, &model.UserSettings{ShareLocation: false}, false},
+		{"share location on", accepted(model.SharingStatusSharing), inbound, &model.UserSettings{ShareLocation: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canView(tt.outbound, tt.inbound, tt.settings); got != tt.want {
+				t.Fatalf("canView = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEntityVisibleSharingStatus(t *testing.T) {
+	ctx := context.Background()
+	const viewer, target = int64(1), int64(2)
+
+	for _, status := range allSharingStatuses {
+		visible := status == "" || status == model.SharingStatusSharing
+
+		// 目标用户 -> 查看者 的共享状态决定可见性
+		t.Run("outbound "+string(status), func(t *testing.T) {
+			s := newPolicyService([]*model.Friend{
+				link(target, viewer, model.FriendStatusAccepted, status),
+				link(viewer, target, model.FriendStatusAccepted, model.SharingStatusSharing),
+			}, nil)
+			err := s.CheckEntityVisible(ctx, viewer, model.GeofenceEntityUser, strconv.FormatInt(target, 10))
+			want := common.PermissionErr.Code
+			if visible {
+				want = 0
+			}
+			if got := errCode(t, err); got != want {
+				t.Fatalf("code = %d, want %d", got, want)
+			}
+		})
+
+		// 查看者自己对目标的共享状态不影响其查看目标
+		t.Run("inbound "+string(status), func(t *testing.T) {
+			s := newPolicyService([]*model.Friend{
+				link(target, viewer, model.FriendStatusAccepted, model.SharingStatusSharing),
+				link(viewer, target, model.FriendStatusAccepted, status),
+			}, nil)
+			err := s.CheckEntityVisible(ctx, viewer, model.GeofenceEntityUser, strconv.FormatInt(target, 10))
+			if got := errCode(t, err); got != 0 {
+				t.Fatalf("code = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestCheckEntityVisible(t *testing.T) {
+	ctx := context.Background()
+	friends := []*model.Friend{
+		link(2, 1, model.FriendStatusAccepted, model.SharingStatusSharing),
+		link(1, 2, model.FriendStatusAccepted, model.SharingStatusSharing),
+		// 3 已解除好友关系，只剩单向记录
+		link(3, 1, model.FriendStatusAccepted, model.SharingStatusSharing),
+		// 4 开启了幽灵模式
+		link(4, 1, model.FriendStatusAccepted, model.SharingStatusSharing),
+		link(1, 4, model.FriendStatusAccepted, model.SharingStatusSharing),
+	}
+	s := newPolicyService(friends, map[int64]*model.UserSettings{
+		4: {UserID: 4, GhostMode: true, ShareLocation: true},
+	})
+
+	tests := []struct {
+		name       string
+		requester  int64
+		entityType string
+		entityID   string
+		want       int
+	}{
+		{"self", 1, model.GeofenceEntityUser, "1", 0},
+		{"self without friends", 9, model.GeofenceEntityUser, "9", 0},
+		{"accepted sharing friend", 1, model.GeofenceEntityUser, "2", 0},
+		{"removed friend", 1, model.GeofenceEntityUser, "3", common.PermissionErr.Code},
+		{"ghost mode friend", 1, model.GeofenceEntityUser, "4", common.PermissionErr.Code},
+		{"stranger", 1, model.GeofenceEntityUser, "5", common.PermissionErr.Code},
+		{"anonymous", 0, model.GeofenceEntityUser, "2", common.AuthErr.Code},
+		{"invalid user id", 1, model.GeofenceEntityUser, "abc", common.ParamErr.Code},
+		{"non-positive user id", 1, model.GeofenceEntityUser, "0", common.ParamErr.Code},
+		{"device owner", 1, model.GeofenceEntityDevice, "dev-1", 0},
+		{"device of friend", 2, model.GeofenceEntityDevice, "dev-1", common.PermissionErr.Code},
+		{"device anonymous", 0, model.GeofenceEntityDevice, "dev-1", common.AuthErr.Code},
+		{"device not found", 1, model.GeofenceEntityDevice, "dev-x", common.DeviceNotFoundErr.Code},
+		{"unknown entity type", 1, "car", "1", common.ParamErr.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckEntityVisible(ctx, tt.requester, tt.entityType, tt.entityID)
+			if got := errCode(t, err); got != tt.want {
+				t.Fatalf("code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewersOf(t *testing.T) {
+	ctx := context.Background()
+	const user = int64(1)
+
+	var friends []*model.Friend
+	var want []int64
+	want = append(want, user)
+	for i, status := range allSharingStatuses {
+		friendID := int64(10 + i)
+		friends = append(friends,
+			link(user, friendID, model.FriendStatusAccepted, status),
+			link(friendID, user, model.FriendStatusAccepted, model.SharingStatusSharing),
+		)
+		if status == "" || status == model.SharingStatusSharing {
+			want = append(want, friendID)
+		}
+	}
+	// 对方未接受、已拒绝或已删除的关系都不推送
+	friends = append(friends,
+		link(user, 20, model.FriendStatusAccepted, model.SharingStatusSharing),
+		link(20, user, model.FriendStatusPending, model.SharingStatusSharing),
+		link(user, 21, model.FriendStatusAccepted, model.SharingStatusSharing),
+		link(21, user, model.FriendStatusRejected, model.SharingStatusSharing),
+		link(user, 22, model.FriendStatusAccepted, model.SharingStatusSharing),
+		link(23, user, model.FriendStatusAccepted, model.SharingStatusSharing),
+	)
+
+	tests := []struct {
+		name     string
+		settings *model.UserSettings
+		want     []int64
+	}{
+		{"default settings", nil, want},
+		{"share location on", &model.UserSettings{UserID: user, ShareLocation: true}, want},
+		{"ghost mode", &model.UserSettings{UserID: user, GhostMode: true, ShareLocation: true}, []int64{user}},
+		{"share location off", &model.UserSettings{UserID: user}, []int64{user}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPolicyService(friends, map[int64]*model.UserSettings{user: tt.settings})
+			got, err := s.viewersOf(ctx, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("viewers = %v, want %v", got, tt.want)
+			}
+
+			// 推送对象与按需查询的可见性一致
+			for _, viewer := range got {
+				if err := s.CheckEntityVisible(ctx, viewer, model.GeofenceEntityUser, strconv.FormatInt(user, 10)); err != nil {
+					t.Fatalf("viewer %d pushed but not visible: %v", viewer, err)
+				}
+			}
+		})
+	}
+}
@@ -3,42 +3,115 @@ package location
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
+	"go.uber.org/zap"
+
+	"app/adaptor/repo/device"
+	"app/adaptor/repo/friend"
 	"app/adaptor/repo/location"
 	"app/adaptor/repo/model"
+	"app/adaptor/repo/settings"
+	"app/adaptor/repo/user"
 	redisCache "app/adaptor/redis"
 	"app/common"
+	"app/service/battery"
+	deviceSvc "app/service/device"
 	"app/service/dto"
+	"app/service/geofence"
+	"app/service/presence"
+	"app/service/visit"
+	"app/service/websocket"
+	"app/utils/logger"
 )
 
 // ILocationService 位置服务接口
 type ILocationService interface {
 	ReportLocation(ctx context.Context, userID int64, req *dto.LocationReportReq) error
-	BatchReportLocation(ctx context.Context, userID int64, req *dto.BatchLocationReportReq) error
+	ReportDeviceLocation(ctx context.Context, userID int64, req *dto.LocationReportReq) error
+	ReportAuthedDeviceLocation(ctx context.Context, device *common.Device, req *dto.LocationReportReq) error
+	BatchReportLocation(ctx context.Context, userID int64, req *dto.BatchLocationReportReq) (*dto.BatchLocationReportResp, error)
 	GetUserLocation(ctx context.Context, userID int64, requesterID int64) (*dto.LocationResp, error)
 	GetDeviceLocation(ctx context.Context, deviceID string, userID int64) (*dto.LocationResp, error)
-	GetLocationHistory(ctx context.Context, req *dto.LocationHistoryReq) ([]*dto.LocationResp, error)
+	GetLocationHistory(ctx context.Context, requesterID int64, req *dto.LocationHistoryReq) ([]*dto.LocationResp, error)
+	GetTrajectory(ctx context.Context, requesterID int64, req *dto.TrajectoryReq) (*dto.TrajectoryResp, error)
 	GetNearbyFriends(ctx context.Context, userID int64, radiusMeters float64) ([]*dto.NearbyFriendResp, error)
 }
 
 // LocationService 位置服务实现
 type LocationService struct {
-	repo  *location.LocationRepository
-	cache *redisCache.LocationCache
+	repo         *location.LocationRepository
+	cache        *redisCache.LocationCache
+	geofence     *geofence.GeofenceService
+	friendRepo   friend.IFriendRepository
+	settingsRepo settings.ISettingsRepository
+	deviceRepo   device.IDeviceRepository
+	devices      *deviceSvc.DeviceService
+	userRepo     user.IUser
+	presence     *presence.PresenceService
+	battery      *battery.BatteryService
+	visits       *visit.VisitService
+	hub          *websocket.Hub
 }
 
 // NewLocationService 创建位置服务
-func NewLocationService(repo *location.LocationRepository, cache *redisCache.LocationCache) *LocationService {
-	return &LocationService{repo: repo, cache: cache}
+func NewLocationService(
+	repo *location.LocationRepository,
+	cache *redisCache.LocationCache,
+	geofence *geofence.GeofenceService,
+	friendRepo friend.IFriendRepository,
+	settingsRepo settings.ISettingsRepository,
+	deviceRepo device.IDeviceRepository,
+	devices *deviceSvc.DeviceService,
+	userRepo user.IUser,
+	presence *presence.PresenceService,
+	battery *battery.BatteryService,
+	visits *visit.VisitService,
+	hub *websocket.Hub,
+) *LocationService {
+	return &LocationService{
+		repo:         repo,
+		cache:        cache,
+		geofence:     geofence,
+		friendRepo:   friendRepo,
+		settingsRepo: settingsRepo,
+		deviceRepo:   deviceRepo,
+		devices:      devices,
+		userRepo:     userRepo,
+		presence:     presence,
+		battery:      battery,
+		visits:       visits,
+		hub:          hub,
+	}
 }
 
-// ReportLocation 上报位置
+// ReportLocation 上报位置，携带 DeviceID 时按设备位置上报
+// 定位时间早于已有最新位置的点（乱序到达）只写入历史，不更新缓存、不触发围栏与推送；
+// 位置点ID已保存过的点（客户端重试）直接返回成功
 func (s *LocationService) ReportLocation(ctx context.Context, userID int64, req *dto.LocationReportReq) error {
-	// 标记低精度位置
-	if req.Accuracy > 0 && req.Accuracy < 100 {
-		req.IsLowAccuracy = true
+	if req.DeviceID != "" {
+		return s.ReportDeviceLocation(ctx, userID, req)
+	}
+	now := time.Now()
+	if reason, detail := validateRecordedAt(req.RecordedAt, now); reason != "" {
+		s.recordRejections(ctx, []*model.RejectedLocation{newRejectedLocation(userID, req, reason, detail)})
+		return rejectErr(reason)
+	}
+	recordedAt := req.RecordedAt
+	if recordedAt.IsZero() {
+		recordedAt = now
 	}
 
+	prev := s.lastAcceptedFix(ctx, userID)
+	cur := &fix{lon: req.Longitude, lat: req.Latitude, accuracy: req.Accuracy, at: recordedAt}
+	if reason, detail := validateFix(cur, prev); reason != "" {
+		s.recordRejections(ctx, []*model.RejectedLocation{newRejectedLocation(userID, req, reason, detail)})
+		return rejectErr(reason)
+	}
+	req.IsLowAccuracy = isLowAccuracy(req)
+
 	loc := &model.UserLocation{
 		UserID:        userID,
 		Accuracy:      req.Accuracy,
@@ -48,13 +121,25 @@ func (s *LocationService) ReportLocation(ctx context.Context, userID int64, req
 		BatteryLevel:  req.BatteryLevel,
 		LocationMode:  model.LocationMode(req.LocationMode),
 		IsLowAccuracy: req.IsLowAccuracy,
+		RecordedAt:    recordedAt,
+		PointID:       pointID(req.PointID),
 	}
 	loc.SetLocation(req.Longitude, req.Latitude)
 
 	// 保存到数据库
-	if err := s.repo.CreateUserLocation(ctx, loc); err != nil {
+	stored, err := s.repo.CreateUserLocation(ctx, loc)
+	if err != nil {
 		return common.DatabaseErr.WithErr(err)
 	}
+	if !stored {
+		return nil
+	}
+
+	s.presence.Touch(ctx, userID)
+	s.visits.Notify(userID, recordedAt)
+	if prev != nil && recordedAt.Before(prev.at) {
+		return nil
+	}
 
 	// 更新缓存
 	resp := s.toLocationResp(loc)
@@ -63,20 +148,89 @@ func (s *LocationService) ReportLocation(ctx context.Context, userID int64, req
 		fmt.Printf("cache user location failed: %v\n", err)
 	}
 
+	s.checkGeofences(ctx, userID, loc)
+	s.battery.CheckUser(ctx, userID, loc.BatteryLevel, loc.RecordedAt)
+	s.pushUserLocation(ctx, userID, resp)
+
 	return nil
 }
 
-// BatchReportLocation 批量上报位置
-func (s *LocationService) BatchReportLocation(ctx context.Context, userID int64, req *dto.BatchLocationReportReq) error {
+// BatchReportLocation 批量上报位置，适用于离线期间缓存的位置补传
+// 位置点按定位时间排序后逐点校验，相邻点之间做速度判定（未上报定位时间的点除外），
+// 位置点ID已保存过、或批次内位置点ID / 定位时间重复的点被跳过，未通过校验的点被跳过并在结果中返回原因；
+// 只有定位时间晚于已有最新位置的点会触发围栏判定，其中最新的点更新缓存并推送
+func (s *LocationService) BatchReportLocation(ctx context.Context, userID int64, req *dto.BatchLocationReportReq) (*dto.BatchLocationReportResp, error) {
+	resp := &dto.BatchLocationReportResp{Stored: []int{}, Duplicated: []int{}, Rejected: []dto.RejectedLocationResp{}}
 	if len(req.Locations) == 0 {
-		return nil
+		return resp, nil
 	}
+	pointIDs := batchPointIDs(req)
+
+	now := time.Now()
+	var rejected []*model.RejectedLocation
+	reject := func(i int, reason model.RejectReason, detail string) {
+		rejected = append(rejected, newRejectedLocation(userID, &req.Locations[i], reason, detail))
+		resp.Rejected = append(resp.Rejected, dto.RejectedLocationResp{Index: i, Reason: string(reason)})
+	}
+
+	// 未上报定位时间的点按接收时间排在最后，保持上报顺序
+	order := make([]int, 0, len(req.Locations))
+	for i := range req.Locations {
+		if reason, detail := validateRecordedAt(req.Locations[i].RecordedAt, now); reason != "" {
+			reject(i, reason, detail)
+			continue
+		}
+		order = append(order, i)
+	}
+	recordedAt := func(i int) time.Time {
+		if at := req.Locations[i].RecordedAt; !at.IsZero() {
+			return at
+		}
+		return now
+	}
+	sort.SliceStable(order, func(a, b int) bool { return recordedAt(order[a]).Before(recordedAt(order[b])) })
+
+	ids := make([]string, 0, len(order))
+	for _, i := range order {
+		if pointIDs[i] != "" {
+			ids = append(ids, pointIDs[i])
+		}
+	}
+	seenIDs, err := s.repo.GetExistingPointIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	prev := s.lastAcceptedFix(ctx, userID)
+	last := prev
+	seenTimes := make(map[int64]bool, len(order))
+	locs := make([]*model.UserLocation, 0, len(order))
+	// indexes[k] 为 locs[k] 在请求中的下标
+	indexes := make([]int, 0, len(order))
+	for _, i := range order {
+		locReq := &req.Locations[i]
+		if id := pointIDs[i]; id != "" {
+			if seenIDs[id] {
+				resp.Duplicated = append(resp.Duplicated, i)
+				continue
+			}
+			seenIDs[id] = true
+		}
+		if !locReq.RecordedAt.IsZero() {
+			key := locReq.RecordedAt.UnixNano()
+			if seenTimes[key] {
+				resp.Duplicated = append(resp.Duplicated, i)
+				continue
+			}
+			seenTimes[key] = true
+		}
 
-	locs := make([]*model.UserLocation, 0, len(req.Locations))
-	for _, locReq := range req.Locations {
-		if locReq.Accuracy > 0 && locReq.Accuracy < 100 {
-			locReq.IsLowAccuracy = true
+		cur := &fix{lon: locReq.Longitude, lat: locReq.Latitude, accuracy: locReq.Accuracy, at: locReq.RecordedAt}
+		if reason, detail := validateFix(cur, prev); reason != "" {
+			reject(i, reason, detail)
+			continue
 		}
+		prev = cur
 
 		loc := &model.UserLocation{
 			UserID:        userID,
@@ -86,29 +240,114 @@ func (s *LocationService) BatchReportLocation(ctx context.Context, userID int64,
 			Bearing:       locReq.Bearing,
 			BatteryLevel:  locReq.BatteryLevel,
 			LocationMode:  model.LocationMode(locReq.LocationMode),
-			IsLowAccuracy: locReq.IsLowAccuracy,
+			IsLowAccuracy: isLowAccuracy(locReq),
+			RecordedAt:    recordedAt(i),
+			PointID:       pointID(pointIDs[i]),
 		}
 		loc.SetLocation(locReq.Longitude, locReq.Latitude)
 		locs = append(locs, loc)
+		indexes = append(indexes, i)
 	}
+	s.recordRejections(ctx, rejected)
 
-	if err := s.repo.BatchCreateUserLocations(ctx, locs); err != nil {
-		return common.DatabaseErr.WithErr(err)
+	created, err := s.repo.BatchCreateUserLocations(ctx, locs)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	// 并发重试已写入的点被跳过，同样视为重复
+	isCreated := make(map[*model.UserLocation]bool, len(created))
+	for _, loc := range created {
+		isCreated[loc] = true
+	}
+	for k, loc := range locs {
+		if isCreated[loc] {
+			resp.Stored = append(resp.Stored, indexes[k])
+		} else {
+			resp.Duplicated = append(resp.Duplicated, indexes[k])
+		}
+	}
+	sort.Slice(resp.Rejected, func(a, b int) bool { return resp.Rejected[a].Index < resp.Rejected[b].Index })
+	sort.Ints(resp.Stored)
+	sort.Ints(resp.Duplicated)
+	resp.Count = len(resp.Stored)
+	resp.Duplicates = len(resp.Duplicated)
+	locs = created
+	if len(locs) == 0 {
+		return resp, nil
+	}
+
+	s.presence.Touch(ctx, userID)
+	// locs 已按定位时间排序
+	s.visits.Notify(userID, locs[0].RecordedAt)
+
+	// 按定位时间逐点判定，保证进出事件与电量告警不遗漏
+	var newest *model.UserLocation
+	for _, loc := range locs {
+		if last != nil && loc.RecordedAt.Before(last.at) {
+			continue
+		}
+		s.checkGeofences(ctx, userID, loc)
+		s.battery.CheckUser(ctx, userID, loc.BatteryLevel, loc.RecordedAt)
+		newest = loc
+	}
+	if newest == nil {
+		return resp, nil
 	}
 
 	// 更新缓存为最新位置
-	if len(locs) > 0 {
-		resp := s.toLocationResp(locs[len(locs)-1])
-		if err := s.cache.SetUserLocation(userID, resp); err != nil {
-			fmt.Printf("cache user location failed: %v\n", err)
+	latest := s.toLocationResp(newest)
+	if err := s.cache.SetUserLocation(userID, latest); err != nil {
+		logger.Warn("cache user location failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	s.pushUserLocation(ctx, userID, latest)
+
+	return resp, nil
+}
+
+// batchPointIDs 获取批量上报中各点的位置点ID，未提供 point_id 时以“幂等键:下标”代替
+func batchPointIDs(req *dto.BatchLocationReportReq) []string {
+	ids := make([]string, len(req.Locations))
+	for i := range req.Locations {
+		switch {
+		case req.Locations[i].PointID != "":
+			ids[i] = req.Locations[i].PointID
+		case req.IdempotencyKey != "":
+			ids[i] = fmt.Sprintf("%s:%d", req.IdempotencyKey, i)
 		}
 	}
+	return ids
+}
 
-	return nil
+// pointID 空字符串保存为 NULL，不参与唯一约束
+func pointID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
 }
 
 // GetUserLocation 获取用户位置
 func (s *LocationService) GetUserLocation(ctx context.Context, userID int64, requesterID int64) (*dto.LocationResp, error) {
+	if err := s.checkUserVisible(ctx, requesterID, userID); err != nil {
+		return nil, err
+	}
+	resp, err := s.getLatestUserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 在线状态获取失败不影响位置查询
+	presences, err := s.presence.GetPresences(ctx, requesterID, []int64{userID})
+	if err != nil {
+		logger.Warn("get presence failed", zap.Int64("user_id", userID), zap.Error(err))
+	} else {
+		resp.Presence = presences[userID]
+	}
+	return resp, nil
+}
+
+// getLatestUserLocation 获取用户最新位置，优先读取缓存
+func (s *LocationService) getLatestUserLocation(ctx context.Context, userID int64) (*dto.LocationResp, error) {
 	// 先从缓存获取
 	resp, err := s.cache.GetUserLocation(userID)
 	if err != nil {
@@ -138,6 +377,14 @@ func (s *LocationService) GetUserLocation(ctx context.Context, userID int64, req
 
 // GetDeviceLocation 获取设备位置
 func (s *LocationService) GetDeviceLocation(ctx context.Context, deviceID string, userID int64) (*dto.LocationResp, error) {
+	if err := s.checkDeviceVisible(ctx, userID, deviceID); err != nil {
+		return nil, err
+	}
+	return s.getLatestDeviceLocation(ctx, deviceID)
+}
+
+// getLatestDeviceLocation 获取设备最新位置，优先读取缓存
+func (s *LocationService) getLatestDeviceLocation(ctx context.Context, deviceID string) (*dto.LocationResp, error) {
 	// 先从缓存获取
 	resp, err := s.cache.GetDeviceLocation(deviceID)
 	if err != nil {
@@ -156,14 +403,7 @@ func (s *LocationService) GetDeviceLocation(ctx context.Context, deviceID string
 		return nil, common.LocationNotFoundErr
 	}
 
-	resp = &dto.LocationResp{
-		DeviceID:     deviceID,
-		Longitude:    loc.Longitude,
-		Latitude:     loc.Latitude,
-		Accuracy:     loc.Accuracy,
-		BatteryLevel: loc.BatteryLevel,
-		CreatedAt:    loc.CreatedAt,
-	}
+	resp = toDeviceLocationResp(loc)
 
 	// 写入缓存
 	if err := s.cache.SetDeviceLocation(deviceID, resp); err != nil {
@@ -174,7 +414,14 @@ func (s *LocationService) GetDeviceLocation(ctx context.Context, deviceID string
 }
 
 // GetLocationHistory 获取位置历史
-func (s *LocationService) GetLocationHistory(ctx context.Context, req *dto.LocationHistoryReq) ([]*dto.LocationResp, error) {
+func (s *LocationService) GetLocationHistory(ctx context.Context, requesterID int64, req *dto.LocationHistoryReq) ([]*dto.LocationResp, error) {
+	if req.UserID == 0 {
+		req.UserID = requesterID
+	}
+	if err := s.checkUserVisible(ctx, requesterID, req.UserID); err != nil {
+		return nil, err
+	}
+
 	limit := req.Limit
 	if limit <= 0 {
 		limit = 100
@@ -194,24 +441,146 @@ func (s *LocationService) GetLocationHistory(ctx context.Context, req *dto.Locat
 }
 
 // GetNearbyFriends 获取附近好友
+// 以调用者最新位置为中心查询 Redis GEO，只返回向调用者共享位置的好友
 func (s *LocationService) GetNearbyFriends(ctx context.Context, userID int64, radiusMeters float64) ([]*dto.NearbyFriendResp, error) {
-	// 先尝试从 Redis GEO 获取
-	friendIDs, err := s.cache.GeoRadiusUsers(0, 0, radiusMeters)
+	origin, err := s.getLatestUserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := s.cache.GeoRadiusUsersWithDist(origin.Longitude, origin.Latitude, radiusMeters)
 	if err != nil {
 		return nil, common.RedisErr.WithErr(err)
 	}
+	if len(hits) == 0 {
+		return []*dto.NearbyFriendResp{}, nil
+	}
+
+	sharing, err := s.sharingFriendIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 如果 Redis GEO 没有数据，使用数据库查询
+	friendIDs := make([]int64, 0, len(hits))
+	distances := make(map[int64]float64, len(hits))
+	for _, hit := range hits {
+		id, err := strconv.ParseInt(hit.Name, 10, 64)
+		if err != nil || !sharing[id] {
+			continue
+		}
+		friendIDs = append(friendIDs, id)
+		distances[id] = hit.Dist
+	}
 	if len(friendIDs) == 0 {
-		// 这里需要结合好友关系查询
-		// 简化处理，返回空列表
 		return []*dto.NearbyFriendResp{}, nil
 	}
 
-	// TODO: 根据好友ID获取详细信息和位置
-	// 这里需要结合 friend repository 和 user service
+	users, err := s.userRepo.GetByIDs(ctx, friendIDs)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	locs, err := s.cache.GetUserLocations(friendIDs)
+	if err != nil {
+		return nil, common.RedisErr.WithErr(err)
+	}
+	// 在线状态获取失败时按离线展示
+	presences, err := s.presence.GetPresences(ctx, userID, friendIDs)
+	if err != nil {
+		logger.Warn("get presence failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
 
-	return []*dto.NearbyFriendResp{}, nil
+	resp := make([]*dto.NearbyFriendResp, 0, len(friendIDs))
+	for _, id := range friendIDs {
+		loc, ok := locs[id]
+		if !ok {
+			// 位置缓存已过期但 GEO 集合仍有残留，回源数据库
+			if loc, err = s.getLatestUserLocation(ctx, id); err != nil {
+				continue
+			}
+		}
+		item := &dto.NearbyFriendResp{
+			UserID:       id,
+			Longitude:    loc.Longitude,
+			Latitude:     loc.Latitude,
+			Distance:     distances[id],
+			BatteryLevel: loc.BatteryLevel,
+			LastActive:   loc.CreatedAt,
+		}
+		if p, ok := presences[id]; ok {
+			item.Online = p.Online
+			if p.LastSeen != nil {
+				item.LastActive = *p.LastSeen
+			}
+		}
+		if u, ok := users[id]; ok {
+			item.Nickname = u.Nickname
+			item.Avatar = u.Avatar
+		}
+		resp = append(resp, item)
+	}
+
+	return resp, nil
+}
+
+// sharingFriendIDs 获取当前向该用户共享位置的好友：
+// 双方为已接受的好友、对方对该用户的共享状态为 sharing，且对方未开启幽灵模式或关闭位置共享
+func (s *LocationService) sharingFriendIDs(ctx context.Context, userID int64) (map[int64]bool, error) {
+	friends, err := s.friendRepo.GetFriends(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	inbound, err := s.friendRepo.GetFriendsOf(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	accepted := make(map[int64]bool, len(friends))
+	for _, f := range friends {
+		accepted[f.FriendID] = true
+	}
+	ids := make([]int64, 0, len(inbound))
+	for _, f := range inbound {
+		if accepted[f.UserID] && isSharing(f.SharingStatus) {
+			ids = append(ids, f.UserID)
+		}
+	}
+
+	userSettings, err := s.settingsRepo.GetByUsers(ctx, ids)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	result := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if st, ok := userSettings[id]; ok && (st.GhostMode || !st.ShareLocation) {
+			continue
+		}
+		result[id] = true
+	}
+	return result, nil
+}
+
+// isSharing 未设置共享状态时按默认值 sharing 处理
+func isSharing(status model.SharingStatus) bool {
+	return status == "" || status == model.SharingStatusSharing
+}
+
+// checkGeofences 检查围栏进出事件，失败不影响上报
+func (s *LocationService) checkGeofences(ctx context.Context, userID int64, loc *model.UserLocation) {
+	entityID := strconv.FormatInt(userID, 10)
+	if _, err := s.geofence.CheckGeofenceEvents(ctx, userID, loc.Longitude, loc.Latitude, loc.Accuracy, model.GeofenceEntityUser, entityID); err != nil {
+		logger.Warn("check geofence events failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// pushUserLocation 向可查看该用户位置的在线好友推送位置更新，失败不影响上报
+func (s *LocationService) pushUserLocation(ctx context.Context, userID int64, resp *dto.LocationResp) {
+	viewers, err := s.viewersOf(ctx, userID)
+	if err != nil {
+		logger.Warn("get location viewers failed", zap.Int64("user_id", userID), zap.Error(err))
+		return
+	}
+	s.hub.PushLocation(websocket.EntityUser, strconv.FormatInt(userID, 10), resp, viewers)
 }
 
 // toLocationResp 转换为响应
@@ -227,6 +596,7 @@ func (s *LocationService) toLocationResp(loc *model.UserLocation) *dto.LocationR
 		Bearing:       loc.Bearing,
 		BatteryLevel:  loc.BatteryLevel,
 		LocationMode:  dto.LocationMode(loc.LocationMode),
+		RecordedAt:    loc.RecordedAt,
 		CreatedAt:     loc.CreatedAt,
 	}
 }
@@ -0,0 +1,97 @@
+package location
+
+import (
+	"context"
+	"time"
+
+	"app/common"
+	"app/service/dto"
+	"app/utils/geo"
+)
+
+const (
+	// 默认简化容差（米）
+	defaultTrajectoryTolerance = 10.0
+
+	// 单次查询的最大时间跨度与最大点数
+	maxTrajectoryRange  = 7 * 24 * time.Hour
+	maxTrajectoryPoints = 200000
+)
+
+// GetTrajectory 获取简化后的轨迹
+// 低精度点不参与轨迹计算；总距离与时长按降采样前的轨迹计算
+func (s *LocationService) GetTrajectory(ctx context.Context, requesterID int64, req *dto.TrajectoryReq) (*dto.TrajectoryResp, error) {
+	if req.UserID == 0 {
+		req.UserID = requesterID
+	}
+	if err := s.checkUserVisible(ctx, requesterID, req.UserID); err != nil {
+		return nil, err
+	}
+	if !req.EndTime.After(req.StartTime) || req.EndTime.Sub(req.StartTime) > maxTrajectoryRange {
+		return nil, common.ParamErr.WithMsg("invalid time range")
+	}
+	if req.Tolerance < 0 || req.Interval < 0 {
+		return nil, common.ParamErr.WithMsg("invalid tolerance or interval")
+	}
+	tolerance := req.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultTrajectoryTolerance
+	}
+
+	locs, err := s.repo.GetUserTrajectory(ctx, req.UserID, req.StartTime, req.EndTime, maxTrajectoryPoints)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	points := make([]dto.TrajectoryPoint, 0, len(locs))
+	for _, loc := range locs {
+		if loc.IsLowAccuracy {
+			continue
+		}
+		points = append(points, dto.TrajectoryPoint{
+			Longitude: loc.Longitude,
+			Latitude:  loc.Latitude,
+			Timestamp: loc.RecordedAt,
+		})
+	}
+
+	resp := &dto.TrajectoryResp{Points: []dto.TrajectoryPoint{}, OriginalCount: len(points)}
+	if len(points) == 0 {
+		return resp, nil
+	}
+	resp.Distance = geo.PathLength(toGeoPoints(points))
+	resp.Duration = int64(points[len(points)-1].Timestamp.Sub(points[0].Timestamp) / time.Second)
+
+	if req.Interval > 0 {
+		points = downsample(points, time.Duration(req.Interval)*time.Second)
+	}
+	for _, i := range geo.Simplify(toGeoPoints(points), tolerance) {
+		resp.Points = append(resp.Points, points[i])
+	}
+	return resp, nil
+}
+
+// downsample 按时间分桶，每个桶保留第一个点，最后一个点始终保留
+func downsample(points []dto.TrajectoryPoint, interval time.Duration) []dto.TrajectoryPoint {
+	if len(points) <= 2 {
+		return points
+	}
+	out := make([]dto.TrajectoryPoint, 0, len(points))
+	var bucket int64
+	for i, p := range points {
+		b := p.Timestamp.UnixNano() / int64(interval)
+		if i == 0 || b != bucket || i == len(points)-1 {
+			out = append(out, p)
+			bucket = b
+		}
+	}
+	return out
+}
+
+func toGeoPoints(points []dto.TrajectoryPoint) []geo.Point {
+	out := make([]geo.Point, len(points))
+	for i, p := range points {
+		out[i] = geo.Point{Lon: p.Longitude, Lat: p.Latitude}
+	}
+	return out
+}
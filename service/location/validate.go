@@ -0,0 +1,137 @@
+package location
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"app/adaptor/repo/model"
+	"app/common"
+	"app/service/dto"
+	"app/utils/geo"
+	"app/utils/logger"
+)
+
+// 位置点校验：
+//   - 经纬度必须在合法范围内，且不能为 (0,0)
+//   - 精度不能为负数，精度值大于 lowAccuracyThreshold 标记为低精度
+//   - 与上一个已接受的定位点相比，扣除双方精度误差后的速度不能超过 maxPlausibleSpeed
+//     （双方时间都已知时才判定，乱序到达的点按时间差的绝对值计算）
+//   - 客户端上报的定位时间不能晚于当前时间 maxClockSkew 以上，也不能早于 maxRecordedAge
+
+const (
+	// 精度半径超过该值（米）视为低精度
+	lowAccuracyThreshold = 100.0
+
+	// 合理的最大移动速度（米/秒），约 1080 km/h，覆盖民航飞行
+	maxPlausibleSpeed = 300.0
+
+	// 计算速度的最小时间间隔，避免间隔过短时速度被放大
+	minSpeedInterval = time.Second
+
+	// 允许的时钟误差，定位时间晚于当前时间超过该值视为非法
+	maxClockSkew = 5 * time.Minute
+
+	// 实时上报允许的最早定位时间，更早的历史位置应通过导入补录
+	maxRecordedAge = 7 * 24 * time.Hour
+)
+
+// fix 参与校验的定位点，at 为定位时间，未知时为零值
+type fix struct {
+	lon      float64
+	lat      float64
+	accuracy float64
+	at       time.Time
+}
+
+// validateFix 校验定位点，prev 为上一个已接受的定位点，可为空
+// 通过时返回空的拒绝原因
+func validateFix(cur, prev *fix) (model.RejectReason, string) {
+	if !geo.ValidCoordinate(cur.lon, cur.lat) {
+		return model.RejectReasonInvalidCoordinate, fmt.Sprintf("lon=%v lat=%v", cur.lon, cur.lat)
+	}
+	if cur.lon == 0 && cur.lat == 0 {
+		return model.RejectReasonNullIsland, ""
+	}
+	if cur.accuracy < 0 || math.IsNaN(cur.accuracy) || math.IsInf(cur.accuracy, 0) {
+		return model.RejectReasonInvalidAccuracy, fmt.Sprintf("accuracy=%v", cur.accuracy)
+	}
+
+	if prev == nil || prev.at.IsZero() || cur.at.IsZero() {
+		return "", ""
+	}
+	dt := cur.at.Sub(prev.at)
+	if dt < 0 {
+		dt = -dt
+	}
+	if dt < minSpeedInterval {
+		dt = minSpeedInterval
+	}
+	dist := geo.Distance(prev.lon, prev.lat, cur.lon, cur.lat) - prev.accuracy - cur.accuracy
+	if speed := dist / dt.Seconds(); speed > maxPlausibleSpeed {
+		return model.RejectReasonImpossibleSpeed, fmt.Sprintf("implied speed %.0f m/s over %s", speed, dt)
+	}
+	return "", ""
+}
+
+// validateRecordedAt 校验客户端上报的定位时间，未上报（零值）时不校验
+func validateRecordedAt(at, now time.Time) (model.RejectReason, string) {
+	if at.IsZero() {
+		return "", ""
+	}
+	if at.After(now.Add(maxClockSkew)) || at.Before(now.Add(-maxRecordedAge)) {
+		return model.RejectReasonInvalidTimestamp, fmt.Sprintf("recorded_at=%s", at.Format(time.RFC3339))
+	}
+	return "", ""
+}
+
+// isLowAccuracy 精度未知（0）时不标记，客户端已标记的保留
+func isLowAccuracy(req *dto.LocationReportReq) bool {
+	return req.IsLowAccuracy || req.Accuracy > lowAccuracyThreshold
+}
+
+// lastAcceptedFix 获取用户上一个已接受的定位点，没有或获取失败时返回空
+func (s *LocationService) lastAcceptedFix(ctx context.Context, userID int64) *fix {
+	loc, err := s.getLatestUserLocation(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	at := loc.RecordedAt
+	if at.IsZero() {
+		// 增加定位时间之前写入的缓存
+		at = loc.CreatedAt
+	}
+	return &fix{lon: loc.Longitude, lat: loc.Latitude, accuracy: loc.Accuracy, at: at}
+}
+
+// rejectErr 将拒绝原因转换为错误码
+func rejectErr(reason model.RejectReason) common.Errno {
+	switch reason {
+	case model.RejectReasonInvalidCoordinate, model.RejectReasonNullIsland:
+		return common.InvalidCoordinatesErr.WithMsg(string(reason))
+	default:
+		return common.LocationRejectedErr.WithMsg(string(reason))
+	}
+}
+
+// recordRejections 记录被拒绝的位置点，失败不影响上报
+func (s *LocationService) recordRejections(ctx context.Context, rejected []*model.RejectedLocation) {
+	if err := s.repo.CreateRejectedLocations(ctx, rejected); err != nil {
+		logger.Warn("record rejected locations failed", zap.Int("count", len(rejected)), zap.Error(err))
+	}
+}
+
+func newRejectedLocation(userID int64, req *dto.LocationReportReq, reason model.RejectReason, detail string) *model.RejectedLocation {
+	return &model.RejectedLocation{
+		UserID:    userID,
+		DeviceID:  req.DeviceID,
+		Longitude: req.Longitude,
+		Latitude:  req.Latitude,
+		Accuracy:  req.Accuracy,
+		Reason:    reason,
+		Detail:    detail,
+	}
+}
@@ -0,0 +1,247 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	redisCache "app/adaptor/redis"
+	"app/adaptor/repo/friend"
+	"app/adaptor/repo/model"
+	"app/adaptor/repo/settings"
+	"app/common"
+	"app/service/dto"
+	"app/service/websocket"
+	"app/utils/logger"
+)
+
+const (
+	// WebSocket 连接的心跳间隔与过期时间，实例异常退出时连接在过期后视为断开
+	connectionHeartbeat = 30 * time.Second
+	connectionTTL       = 90 * time.Second
+
+	// 最近一次位置上报后视为在线的时长
+	reportTTL    = 5 * time.Minute
+	reportSource = "report"
+
+	// 超时离线扫描
+	sweepInterval  = 15 * time.Second
+	sweepBatchSize = 100
+)
+
+// 在线状态可见性：
+//   - 用户本人始终可见
+//   - 双方均为已接受的好友，且对方未开启幽灵模式、对查看者的共享状态不是 hidden
+//     （暂停位置共享 paused 时仍可见在线状态）
+
+// PresenceService 在线状态服务
+type PresenceService struct {
+	cache        *redisCache.PresenceCache
+	friendRepo   *friend.FriendRepository
+	settingsRepo *settings.SettingsRepository
+	hub          *websocket.Hub
+
+	// 实例标识，用于区分不同实例上的连接
+	instance string
+}
+
+// NewPresenceService 创建在线状态服务
+func NewPresenceService(
+	cache *redisCache.PresenceCache,
+	friendRepo *friend.FriendRepository,
+	settingsRepo *settings.SettingsRepository,
+	hub *websocket.Hub,
+) *PresenceService {
+	host, _ := os.Hostname()
+	return &PresenceService{
+		cache:        cache,
+		friendRepo:   friendRepo,
+		settingsRepo: settingsRepo,
+		hub:          hub,
+		instance:     fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Run 定期扫描超时未心跳的用户并推送离线事件
+func (s *PresenceService) Run() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+func (s *PresenceService) sweep() {
+	userIDs, err := s.cache.ExpiredUsers(sweepBatchSize)
+	if err != nil {
+		logger.Warn("presence sweep error", zap.Error(err))
+		return
+	}
+	for _, userID := range userIDs {
+		offline, err := s.cache.Expire(userID)
+		if err != nil {
+			logger.Warn("presence expire error", zap.Error(err), zap.Int64("user_id", userID))
+			continue
+		}
+		if offline {
+			s.notify(context.Background(), userID, false)
+		}
+	}
+}
+
+// Track 记录 WebSocket 连接上线并定期心跳，返回的 stop 在连接断开时调用
+func (s *PresenceService) Track(userID int64, connID string) (stop func()) {
+	source := fmt.Sprintf("ws:%s:%s", s.instance, connID)
+	s.touch(context.Background(), userID, source, connectionTTL)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(connectionHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.touch(context.Background(), userID, source, connectionTTL)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		offline, err := s.cache.Remove(userID, source)
+		if err != nil {
+			logger.Warn("presence remove error", zap.Error(err), zap.Int64("user_id", userID))
+			return
+		}
+		if offline {
+			s.notify(context.Background(), userID, false)
+		}
+	}
+}
+
+// Touch 位置上报时刷新在线状态
+func (s *PresenceService) Touch(ctx context.Context, userID int64) {
+	s.touch(ctx, userID, reportSource, reportTTL)
+}
+
+func (s *PresenceService) touch(ctx context.Context, userID int64, source string, ttl time.Duration) {
+	online, err := s.cache.Touch(userID, source, ttl)
+	if err != nil {
+		logger.Warn("presence touch error", zap.Error(err), zap.Int64("user_id", userID))
+		return
+	}
+	if online {
+		s.notify(ctx, userID, true)
+	}
+}
+
+// GetPresences 获取 viewerID 可见的用户在线状态，不可见的用户不出现在结果中
+func (s *PresenceService) GetPresences(ctx context.Context, viewerID int64, userIDs []int64) (map[int64]*dto.PresenceResp, error) {
+	visible, err := s.visibleTo(ctx, viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	presences, err := s.cache.GetPresences(visible)
+	if err != nil {
+		return nil, common.RedisErr.WithErr(err)
+	}
+	return presences, nil
+}
+
+// visibleTo 过滤出 viewerID 可以查看在线状态的用户
+func (s *PresenceService) visibleTo(ctx context.Context, viewerID int64, userIDs []int64) ([]int64, error) {
+	// 对方的好友记录中保存对方向查看者的共享状态
+	outbound, err := s.friendRepo.GetFriendsOf(ctx, viewerID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	inbound, err := s.friendRepo.GetFriends(ctx, viewerID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	userSettings, err := s.settingsRepo.GetByUsers(ctx, userIDs)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	accepted := make(map[int64]bool, len(inbound))
+	for _, f := range inbound {
+		accepted[f.FriendID] = true
+	}
+	shown := make(map[int64]bool, len(outbound))
+	for _, f := range outbound {
+		if accepted[f.UserID] && f.SharingStatus != model.SharingStatusHidden {
+			shown[f.UserID] = true
+		}
+	}
+
+	visible := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != viewerID {
+			if !shown[id] {
+				continue
+			}
+			if st := userSettings[id]; st != nil && st.GhostMode {
+				continue
+			}
+		}
+		visible = append(visible, id)
+	}
+	return visible, nil
+}
+
+// audienceOf 获取可以看到该用户在线状态的好友
+func (s *PresenceService) audienceOf(ctx context.Context, userID int64) ([]int64, error) {
+	st, err := s.settingsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if st != nil && st.GhostMode {
+		return nil, nil
+	}
+
+	outbound, err := s.friendRepo.GetFriends(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, err
+	}
+	inbound, err := s.friendRepo.GetFriendsOf(ctx, userID, string(model.FriendStatusAccepted))
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[int64]bool, len(inbound))
+	for _, f := range inbound {
+		accepted[f.UserID] = true
+	}
+	var audience []int64
+	for _, f := range outbound {
+		if accepted[f.FriendID] && f.SharingStatus != model.SharingStatusHidden {
+			audience = append(audience, f.FriendID)
+		}
+	}
+	return audience, nil
+}
+
+// notify 向好友推送在线状态变化
+func (s *PresenceService) notify(ctx context.Context, userID int64, online bool) {
+	audience, err := s.audienceOf(ctx, userID)
+	if err != nil {
+		logger.Warn("presence audience error", zap.Error(err), zap.Int64("user_id", userID))
+		return
+	}
+
+	msg := websocket.NewMessage(websocket.MessageTypePresenceChanged, &dto.PresenceChangedEvent{
+		UserID:   userID,
+		Online:   online,
+		LastSeen: time.Now(),
+	})
+	for _, friendID := range audience {
+		s.hub.SendToUser(friendID, msg)
+	}
+}
@@ -0,0 +1,184 @@
+package trip
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"app/adaptor/repo/location"
+	"app/common"
+	"app/service/dto"
+	"app/utils/geo"
+)
+
+// 行程划分：
+//   - 连续的位置点都在首个点 stationaryRadius 米内且持续至少 stationaryDuration 视为静止，
+//     静止期之间的位置点构成一次行程，行程从离开前的最后一个点开始，到到达后的第一个点结束
+//   - 相邻两点间隔超过 maxPointGap 时无法还原中间的移动，行程在此断开
+//   - 距离不足 minTripDistance 的行程视为定位漂移，不返回
+//
+// 移动方式按行程内速度的 85 分位数判断，避免个别异常点的影响；
+// 位置点上报了速度时使用上报值，否则用与上一个点的距离和时间间隔估算
+
+const (
+	stationaryRadius   = 100.0
+	stationaryDuration = 5 * time.Minute
+	maxPointGap        = 15 * time.Minute
+	minTripDistance    = 200.0
+
+	// 平均速度低于该值（米/秒）的行程视为静止（如在较大范围内徘徊）
+	stationaryMaxSpeed = 0.5
+	// 速度 85 分位数不超过该值（米/秒）时分别视为步行、骑行，否则为驾车
+	walkingMaxSpeed = 2.8 // 约 10 km/h
+	cyclingMaxSpeed = 8.3 // 约 30 km/h
+
+	// 单次查询的最大时间跨度与最大点数
+	maxTripRange  = 7 * 24 * time.Hour
+	maxTripPoints = 200000
+)
+
+// ITripService 行程服务接口
+type ITripService interface {
+	GetTrips(ctx context.Context, userID int64, req *dto.TripListReq) ([]*dto.TripResp, error)
+}
+
+// TripService 行程服务实现
+type TripService struct {
+	locationRepo *location.LocationRepository
+}
+
+// NewTripService 创建行程服务
+func NewTripService(locationRepo *location.LocationRepository) *TripService {
+	return &TripService{locationRepo: locationRepo}
+}
+
+// tripPoint 参与行程划分的位置点，speed 为上报的速度，未上报时为 0
+type tripPoint struct {
+	lon   float64
+	lat   float64
+	speed float64
+	at    time.Time
+}
+
+// GetTrips 获取时间段内的行程，按开始时间正序
+// 低精度点不参与计算
+func (s *TripService) GetTrips(ctx context.Context, userID int64, req *dto.TripListReq) ([]*dto.TripResp, error) {
+	if !req.EndTime.After(req.StartTime) || req.EndTime.Sub(req.StartTime) > maxTripRange {
+		return nil, common.ParamErr.WithMsg("invalid time range")
+	}
+
+	locs, err := s.locationRepo.GetUserTrajectory(ctx, userID, req.StartTime, req.EndTime, maxTripPoints)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	points := make([]tripPoint, 0, len(locs))
+	for _, loc := range locs {
+		if loc.IsLowAccuracy {
+			continue
+		}
+		points = append(points, tripPoint{lon: loc.Longitude, lat: loc.Latitude, speed: loc.Speed, at: loc.RecordedAt})
+	}
+
+	trips := []*dto.TripResp{}
+	for _, segment := range segmentTrips(points) {
+		if trip := summarize(segment); trip.Distance >= minTripDistance {
+			trips = append(trips, trip)
+		}
+	}
+	return trips, nil
+}
+
+// segmentTrips 按静止期和长时间间隔划分行程，每段至少包含两个点
+func segmentTrips(points []tripPoint) [][]tripPoint {
+	var segments [][]tripPoint
+	start := 0
+	flush := func(end int) {
+		if end-start >= 2 {
+			segments = append(segments, points[start:end])
+		}
+	}
+
+	for i := 0; i < len(points); {
+		if i > 0 && points[i].at.Sub(points[i-1].at) > maxPointGap {
+			flush(i)
+			start = i
+		}
+
+		j := i + 1
+		for j < len(points) && distance(points[i], points[j]) <= stationaryRadius {
+			j++
+		}
+		if points[j-1].at.Sub(points[i].at) < stationaryDuration {
+			i++
+			continue
+		}
+		// points[i:j] 为静止期，到达点结束上一段行程，离开点开始下一段
+		flush(i + 1)
+		start = j - 1
+		i = j
+	}
+	flush(len(points))
+	return segments
+}
+
+// summarize 计算行程摘要
+func summarize(points []tripPoint) *dto.TripResp {
+	first, last := points[0], points[len(points)-1]
+	trip := &dto.TripResp{
+		StartTime:      first.at,
+		EndTime:        last.at,
+		StartLongitude: first.lon,
+		StartLatitude:  first.lat,
+		EndLongitude:   last.lon,
+		EndLatitude:    last.lat,
+		Duration:       int64(last.at.Sub(first.at) / time.Second),
+		PointCount:     len(points),
+	}
+
+	speeds := make([]float64, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		d := distance(points[i-1], points[i])
+		trip.Distance += d
+
+		speed := points[i].speed
+		if speed <= 0 {
+			dt := points[i].at.Sub(points[i-1].at)
+			if dt < time.Second {
+				continue
+			}
+			speed = d / dt.Seconds()
+		}
+		speeds = append(speeds, speed)
+		if speed > trip.MaxSpeed {
+			trip.MaxSpeed = speed
+		}
+	}
+	if trip.Duration > 0 {
+		trip.AvgSpeed = trip.Distance / float64(trip.Duration)
+	}
+	trip.Mode = inferMode(trip.AvgSpeed, speeds)
+	return trip
+}
+
+// inferMode 根据平均速度和速度分布推断移动方式
+func inferMode(avgSpeed float64, speeds []float64) dto.MovementMode {
+	if avgSpeed < stationaryMaxSpeed || len(speeds) == 0 {
+		return dto.MovementModeStationary
+	}
+	sorted := append([]float64(nil), speeds...)
+	sort.Float64s(sorted)
+	typical := sorted[len(sorted)*85/100]
+
+	switch {
+	case typical <= walkingMaxSpeed:
+		return dto.MovementModeWalking
+	case typical <= cyclingMaxSpeed:
+		return dto.MovementModeCycling
+	default:
+		return dto.MovementModeDriving
+	}
+}
+
+func distance(a, b tripPoint) float64 {
+	return geo.Distance(a.lon, a.lat, b.lon, b.lat)
+}
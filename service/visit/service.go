@@ -0,0 +1,337 @@
+package visit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	redisCache "app/adaptor/redis"
+	"app/adaptor/repo/location"
+	"app/adaptor/repo/model"
+	"app/adaptor/repo/visit"
+	"app/common"
+	"app/service/dto"
+	"app/utils/geo"
+	"app/utils/logger"
+)
+
+const (
+	// 停留点判定：连续的位置点都在首个点 stayDistance 米内，且持续至少 stayDuration
+	stayDistance = 100.0
+	stayDuration = 10 * time.Minute
+
+	// 停留中心距已有地点中心在该范围内（米）时归入该地点
+	placeRadius = 150.0
+
+	// 每次检测最多回溯的时间与读取的点数
+	detectWindow    = 7 * 24 * time.Hour
+	detectMaxPoints = 50000
+
+	// 后台检测间隔与每批处理的用户数
+	detectInterval  = time.Minute
+	detectBatchSize = 100
+
+	// 停留记录默认查询范围
+	defaultVisitRange = 30 * 24 * time.Hour
+)
+
+// IVisitService 停留与地点服务接口
+type IVisitService interface {
+	Notify(userID int64, since time.Time)
+	Detect(ctx context.Context, userID int64, since time.Time) error
+	GetVisits(ctx context.Context, userID int64, req *dto.VisitListReq) ([]*dto.VisitResp, error)
+	GetPlaces(ctx context.Context, userID int64) ([]*dto.PlaceResp, error)
+	UpdatePlace(ctx context.Context, userID, placeID int64, req *dto.PlaceUpdateReq) error
+}
+
+// VisitService 停留与地点服务实现
+type VisitService struct {
+	repo         *visit.VisitRepository
+	locationRepo *location.LocationRepository
+	cache        *redisCache.VisitCache
+}
+
+// NewVisitService 创建停留与地点服务
+func NewVisitService(repo *visit.VisitRepository, locationRepo *location.LocationRepository, cache *redisCache.VisitCache) *VisitService {
+	return &VisitService{repo: repo, locationRepo: locationRepo, cache: cache}
+}
+
+// stayPoint 参与停留检测的位置点
+type stayPoint struct {
+	lon float64
+	lat float64
+	at  time.Time
+}
+
+// Notify 记录用户有新保存的位置点，由后台任务检测停留；since 为这些点中最早的定位时间
+func (s *VisitService) Notify(userID int64, since time.Time) {
+	if err := s.cache.MarkPending(userID, since); err != nil {
+		logger.Warn("mark visit detection pending error", zap.Error(err), zap.Int64("user_id", userID))
+	}
+}
+
+// Run 定期为有新位置点的用户检测停留
+func (s *VisitService) Run() {
+	ticker := time.NewTicker(detectInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.detectPending()
+	}
+}
+
+func (s *VisitService) detectPending() {
+	ctx := context.Background()
+	var failed []redisCache.PendingVisit
+	for {
+		pending, err := s.cache.PopPending(detectBatchSize)
+		if err != nil {
+			logger.Warn("visit detection pop error", zap.Error(err))
+			break
+		}
+		for _, p := range pending {
+			if err := s.Detect(ctx, p.UserID, p.Since); err != nil {
+				logger.Warn("visit detection error", zap.Error(err), zap.Int64("user_id", p.UserID))
+				failed = append(failed, p)
+			}
+		}
+		if len(pending) < detectBatchSize {
+			break
+		}
+	}
+	// 检测失败的用户放回队列，下个周期重试
+	for _, p := range failed {
+		s.Notify(p.UserID, p.Since)
+	}
+}
+
+// Detect 检测上次停留之后新产生的停留，并归入已有地点或创建新地点
+// since 为新保存位置点中最早的定位时间：早于已检测的最后一次停留的离开时间时（补传、导入的点），
+// 先回滚受影响的停留，再从回滚点重新检测。
+// 仍在进行中的停留（之后还没有离开的位置点）不会写入，留待下次检测
+func (s *VisitService) Detect(ctx context.Context, userID int64, since time.Time) error {
+	now := time.Now()
+	start := now.Add(-detectWindow)
+	last, err := s.repo.GetLastVisit(ctx, userID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if last != nil && !since.IsZero() && !since.After(last.DepartedAt) {
+		if last, err = s.rollback(ctx, userID, since, start); err != nil {
+			return err
+		}
+	}
+	if last != nil && last.DepartedAt.After(start) {
+		// 离开时刻的位置点属于上一次停留
+		start = last.DepartedAt.Add(time.Second)
+	}
+
+	locs, err := s.locationRepo.GetUserTrajectory(ctx, userID, start, now, detectMaxPoints)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	points := make([]stayPoint, 0, len(locs))
+	for _, loc := range locs {
+		if loc.IsLowAccuracy {
+			continue
+		}
+		points = append(points, stayPoint{lon: loc.Longitude, lat: loc.Latitude, at: loc.RecordedAt})
+	}
+
+	stays := detectStays(points)
+	if len(stays) == 0 {
+		return nil
+	}
+
+	places, err := s.repo.GetPlaces(ctx, userID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	for _, stay := range stays {
+		stay.UserID = userID
+		place := nearestPlace(places, stay.Longitude, stay.Latitude)
+		isNew := place == nil
+		if isNew {
+			place = &model.Place{UserID: userID, Longitude: stay.Longitude, Latitude: stay.Latitude}
+		} else {
+			n := float64(place.VisitCount)
+			place.Longitude = (place.Longitude*n + stay.Longitude) / (n + 1)
+			place.Latitude = (place.Latitude*n + stay.Latitude) / (n + 1)
+		}
+		place.VisitCount++
+		place.TotalDuration += int64(stay.Duration() / time.Second)
+		place.LastVisitAt = stay.DepartedAt
+
+		saved, err := s.repo.SaveVisit(ctx, place, stay)
+		if err != nil {
+			return common.DatabaseErr.WithErr(err)
+		}
+		if !saved {
+			// 其他请求已写入同一停留，由其完成本轮检测
+			return nil
+		}
+		if isNew {
+			places = append(places, place)
+		}
+	}
+	return nil
+}
+
+// rollback 回滚 since 之后的停留，返回回滚后的最后一次停留
+// 新的点可能延长在它之前结束的那次停留，该停留在检测范围内时一并回滚
+func (s *VisitService) rollback(ctx context.Context, userID int64, since, windowStart time.Time) (*model.Visit, error) {
+	from := since
+	prev, err := s.repo.GetLastVisitBefore(ctx, userID, since)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	if prev != nil && !prev.ArrivedAt.Before(windowStart) {
+		from = prev.DepartedAt
+	}
+	if _, err := s.repo.RollbackVisits(ctx, userID, from); err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	last, err := s.repo.GetLastVisit(ctx, userID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	return last, nil
+}
+
+// detectStays 按时间顺序扫描位置点，识别停留
+func detectStays(points []stayPoint) []*model.Visit {
+	var stays []*model.Visit
+	for i := 0; i < len(points); {
+		j := i + 1
+		for j < len(points) && geo.Distance(points[i].lon, points[i].lat, points[j].lon, points[j].lat) <= stayDistance {
+			j++
+		}
+		dwell := points[j-1].at.Sub(points[i].at)
+		if dwell < stayDuration {
+			i++
+			continue
+		}
+		if j == len(points) {
+			// 尚未离开，停留仍在进行中
+			break
+		}
+
+		var lon, lat float64
+		for _, p := range points[i:j] {
+			lon += p.lon
+			lat += p.lat
+		}
+		n := float64(j - i)
+		stays = append(stays, &model.Visit{
+			Longitude:  lon / n,
+			Latitude:   lat / n,
+			ArrivedAt:  points[i].at,
+			DepartedAt: points[j-1].at,
+			PointCount: j - i,
+		})
+		i = j
+	}
+	return stays
+}
+
+// nearestPlace 查找 placeRadius 内最近的地点
+func nearestPlace(places []*model.Place, lon, lat float64) *model.Place {
+	var (
+		nearest *model.Place
+		best    = math.Inf(1)
+	)
+	for _, p := range places {
+		if d := geo.Distance(p.Longitude, p.Latitude, lon, lat); d <= placeRadius && d < best {
+			nearest, best = p, d
+		}
+	}
+	return nearest
+}
+
+// GetVisits 获取已检测出的停留记录
+func (s *VisitService) GetVisits(ctx context.Context, userID int64, req *dto.VisitListReq) ([]*dto.VisitResp, error) {
+	endTime := req.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := req.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.Add(-defaultVisitRange)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	visits, err := s.repo.GetVisits(ctx, userID, startTime, endTime, limit, req.Offset)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+	placeIDs := make([]int64, 0, len(visits))
+	for _, v := range visits {
+		placeIDs = append(placeIDs, v.PlaceID)
+	}
+	places, err := s.repo.GetPlacesByIDs(ctx, placeIDs)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	resp := make([]*dto.VisitResp, len(visits))
+	for i, v := range visits {
+		resp[i] = &dto.VisitResp{
+			ID:         v.ID,
+			PlaceID:    v.PlaceID,
+			Longitude:  v.Longitude,
+			Latitude:   v.Latitude,
+			ArrivedAt:  v.ArrivedAt,
+			DepartedAt: v.DepartedAt,
+			Duration:   int64(v.Duration() / time.Second),
+		}
+		if p, ok := places[v.PlaceID]; ok {
+			resp[i].PlaceName = p.Name
+		}
+	}
+	return resp, nil
+}
+
+// GetPlaces 获取用户的地点
+func (s *VisitService) GetPlaces(ctx context.Context, userID int64) ([]*dto.PlaceResp, error) {
+	places, err := s.repo.GetPlaces(ctx, userID)
+	if err != nil {
+		return nil, common.DatabaseErr.WithErr(err)
+	}
+
+	resp := make([]*dto.PlaceResp, len(places))
+	for i, p := range places {
+		resp[i] = &dto.PlaceResp{
+			ID:            p.ID,
+			Name:          p.Name,
+			Longitude:     p.Longitude,
+			Latitude:      p.Latitude,
+			VisitCount:    p.VisitCount,
+			TotalDuration: p.TotalDuration,
+			LastVisitAt:   p.LastVisitAt,
+		}
+	}
+	return resp, nil
+}
+
+// UpdatePlace 为地点命名
+func (s *VisitService) UpdatePlace(ctx context.Context, userID, placeID int64, req *dto.PlaceUpdateReq) error {
+	place, err := s.repo.GetPlace(ctx, placeID)
+	if err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	if place == nil {
+		return common.PlaceNotFoundErr
+	}
+	if place.UserID != userID {
+		return common.PermissionErr
+	}
+	if err := s.repo.UpdatePlaceName(ctx, placeID, req.Name); err != nil {
+		return common.DatabaseErr.WithErr(err)
+	}
+	return nil
+}
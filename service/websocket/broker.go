@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+
+	redisCache "app/adaptor/redis"
+	"app/utils/logger"
+)
+
+const (
+	// 订阅连接空闲时的探活间隔
+	brokerPingPeriod = 30 * time.Second
+	// 订阅断开后的重连退避
+	brokerMinBackoff = 500 * time.Millisecond
+	brokerMaxBackoff = 30 * time.Second
+)
+
+// UseBroker 启用 Redis Pub/Sub 跨实例转发，需在 Run 之前、开始推送之前调用
+// 所有实例（包括发布者自身）都通过订阅收到消息后再投递给本实例的连接
+func (h *Hub) UseBroker(pubsub *redisCache.PubSub) {
+	h.broker = pubsub
+	go h.consumeBroker()
+}
+
+// consumeBroker 持续消费订阅消息，订阅断开时按指数退避重连
+func (h *Hub) consumeBroker() {
+	backoff := brokerMinBackoff
+	for {
+		start := time.Now()
+		err := h.receiveBroker()
+
+		// 订阅稳定运行过一段时间后再断开，重新从最小退避开始
+		if time.Since(start) > brokerMaxBackoff {
+			backoff = brokerMinBackoff
+		}
+		logger.Warn("websocket broker subscription dropped, reconnecting",
+			zap.Error(err), zap.Duration("backoff", backoff))
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > brokerMaxBackoff {
+			backoff = brokerMaxBackoff
+		}
+	}
+}
+
+// receiveBroker 建立订阅并分发消息，直到连接出错
+func (h *Hub) receiveBroker() error {
+	ps, err := h.broker.SubscribeRealtime()
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+
+	for {
+		msg, err := ps.ReceiveTimeout(brokerPingPeriod)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				if err := ps.Ping(); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			logger.Debug("websocket broker subscribed", zap.String("channel", m.Channel))
+		case *redis.Message:
+			h.dispatchBrokerMessage(m)
+		}
+	}
+}
+
+// dispatchBrokerMessage 将订阅收到的消息投递给本实例的连接
+func (h *Hub) dispatchBrokerMessage(m *redis.Message) {
+	if redisCache.IsLocationUpdateChannel(m.Channel) {
+		var update redisCache.LocationUpdate
+		if err := json.Unmarshal([]byte(m.Payload), &update); err != nil {
+			logger.Warn("decode location update failed", zap.Error(err))
+			return
+		}
+		h.pushLocationLocal(update.EntityType, update.EntityID, update.Location, update.Viewers)
+		return
+	}
+
+	if userID, ok := redisCache.ParseWSMessageChannel(m.Channel); ok {
+		h.sendToUserLocal(userID, []byte(m.Payload))
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
 
+	redisCache "app/adaptor/redis"
 	"app/service/dto"
+	"app/utils/logger"
 )
 
 const (
@@ -16,6 +19,35 @@ const (
 	pongWait       = 30 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512 * 1024 // 512KB
+
+	// 单个连接最多订阅的实体数
+	MaxSubscriptions = 200
+
+	// 连续丢弃的消息数达到该值时断开慢连接
+	maxDroppedMessages = 32
+
+	// 同一连接对同一实体的位置推送最小间隔，间隔内的更新只保留最新一条延后发送
+	locationPushInterval = 2 * time.Second
+	pushFlushPeriod      = 500 * time.Millisecond
+)
+
+// 订阅实体类型
+const (
+	EntityUser   = "user"
+	EntityDevice = "device"
+)
+
+// 消息类型
+const (
+	MessageTypeLocationUpdate  = "location_update"
+	MessageTypeSubscribeAck    = "subscribe_ack"
+	MessageTypeUnsubscribeAck  = "unsubscribe_ack"
+	MessageTypePong            = "pong"
+	MessageTypeAck             = "ack"
+	MessageTypeError           = "error"
+	MessageTypePresenceChanged = "presence_changed"
+	MessageTypeDeviceStatus    = "device_status_changed"
+	MessageTypeBatteryAlert    = "battery_alert"
 )
 
 // Client WebSocket客户端
@@ -27,28 +59,59 @@ type Client struct {
 	Send     chan []byte
 	mu       sync.Mutex
 	IsClosed bool
+
+	// 订阅的实体，键为 "<type>:<id>"
+	subscriptions map[string]bool
+	subMu         sync.RWMutex
+
+	// 以下字段仅由 Hub.Run 所在协程访问
+	lastPush    map[string]time.Time // 位置推送节流状态
+	pendingPush map[string][]byte
+	dropped     int // 连续因发送队列已满而丢弃的消息数
 }
 
 // Hub WebSocket连接管理器
+// 连接表只在 Run 所在协程中修改，所有投递都经由 broadcast 通道进入 Run，
+// 因此发送与关闭 Client.Send 不会并发发生；其他协程只读时持有 mu 读锁
 type Hub struct {
-	clients    map[*Client]bool
-	broadcast  chan *Message
+	clients map[*Client]struct{}
+	users   map[int64]map[*Client]struct{} // 用户ID -> 该用户的连接
+	pending map[*Client]struct{}           // 有待发送节流消息的连接
+	mu      sync.RWMutex
+
+	broadcast  chan *broadcastMessage
 	register   chan *Client
 	unregister chan *Client
-	mu         sync.RWMutex
+
+	// 多实例部署时通过 Redis Pub/Sub 转发，为空时只在本实例内投递
+	broker *redisCache.PubSub
+}
+
+// broadcastMessage 待投递的消息
+//   - client 非空时只发送给该连接
+//   - viewers 为空时发送给所有连接
+//   - 否则发送给 viewers 中用户的连接，entityKey 非空时还按订阅过滤并节流
+type broadcastMessage struct {
+	client    *Client
+	entityKey string
+	viewers   map[int64]bool
+	data      []byte
 }
 
 // Message WebSocket消息
 type Message struct {
 	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"` // 客户端请求ID，应答时原样带回
 	Payload json.RawMessage `json:"payload"`
 }
 
 // NewHub 创建Hub
 func NewHub() *Hub {
 	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan *Message, 256),
+		clients:    make(map[*Client]struct{}),
+		users:      make(map[int64]map[*Client]struct{}),
+		pending:    make(map[*Client]struct{}),
+		broadcast:  make(chan *broadcastMessage, 1024),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -56,32 +119,118 @@ func NewHub() *Hub {
 
 // Run 启动Hub
 func (h *Hub) Run() {
+	flush := time.NewTicker(pushFlushPeriod)
+	defer flush.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
+			h.addClient(client)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.Send <- marshalMessage(message):
-				default:
-					close(client.Send)
-					delete(h.clients, client)
+			h.deliver(message, time.Now())
+
+		case now := <-flush.C:
+			h.flushPending(now)
+		}
+	}
+}
+
+func (h *Hub) addClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client] = struct{}{}
+	set, ok := h.users[client.UserID]
+	if !ok {
+		set = make(map[*Client]struct{})
+		h.users[client.UserID] = set
+	}
+	set[client] = struct{}{}
+}
+
+// removeClient 移除连接并关闭发送队列，重复移除是安全的
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	delete(h.pending, client)
+	if set, ok := h.users[client.UserID]; ok {
+		delete(set, client)
+		if len(set) == 0 {
+			delete(h.users, client.UserID)
+		}
+	}
+	close(client.Send)
+}
+
+func (h *Hub) deliver(message *broadcastMessage, now time.Time) {
+	switch {
+	case message.client != nil:
+		if _, ok := h.clients[message.client]; ok {
+			h.send(message.client, message.data)
+		}
+	case message.viewers == nil:
+		for client := range h.clients {
+			h.send(client, message.data)
+		}
+	default:
+		for userID := range message.viewers {
+			for client := range h.users[userID] {
+				if message.entityKey == "" {
+					h.send(client, message.data)
+				} else if client.wants(message.entityKey) {
+					h.pushThrottled(client, message.entityKey, message.data, now)
 				}
 			}
-			h.mu.RUnlock()
+		}
+	}
+}
+
+// pushThrottled 节流推送：间隔内的更新只保留最新一条，由 flushPending 延后发送
+func (h *Hub) pushThrottled(client *Client, key string, data []byte, now time.Time) {
+	if now.Sub(client.lastPush[key]) < locationPushInterval {
+		client.pendingPush[key] = data
+		h.pending[client] = struct{}{}
+		return
+	}
+	client.lastPush[key] = now
+	delete(client.pendingPush, key)
+	h.send(client, data)
+}
+
+func (h *Hub) flushPending(now time.Time) {
+	for client := range h.pending {
+		for key, data := range client.pendingPush {
+			if now.Sub(client.lastPush[key]) < locationPushInterval {
+				continue
+			}
+			client.lastPush[key] = now
+			delete(client.pendingPush, key)
+			h.send(client, data)
+		}
+		if len(client.pendingPush) == 0 {
+			delete(h.pending, client)
+		}
+	}
+}
+
+// send 非阻塞发送：发送队列已满时丢弃消息，连续丢弃过多的慢连接将被断开
+func (h *Hub) send(client *Client, data []byte) {
+	select {
+	case client.Send <- data:
+		client.dropped = 0
+	default:
+		client.dropped++
+		if client.dropped >= maxDroppedMessages {
+			logger.Warn("evict slow websocket client",
+				zap.String("client_id", client.ID), zap.Int64("user_id", client.UserID))
+			h.removeClient(client)
 		}
 	}
 }
@@ -96,44 +245,135 @@ func (h *Hub) Unregister(client *Client) {
 	h.unregister <- client
 }
 
+// IsOnline 用户在本实例是否有连接
+func (h *Hub) IsOnline(userID int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// ConnectionCount 本实例的连接数
+func (h *Hub) ConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
 // Broadcast 广播消息
 func (h *Hub) Broadcast(message *Message) {
-	h.broadcast <- message
+	h.broadcast <- &broadcastMessage{data: marshalMessage(message)}
 }
 
-// BroadcastLocation 广播位置更新
-func (h *Hub) BroadcastLocation(entityType string, entityID string, loc *dto.LocationResp) {
-	msg := &Message{
-		Type: "location_update",
-		Payload: mustMarshal(map[string]interface{}{
-			"entity_type": entityType,
-			"entity_id":   entityID,
-			"location":    loc,
-		}),
+// PushLocation 向有权查看该实体的用户推送位置更新
+// 连接只接收已订阅实体的推送，未订阅任何实体的连接不接收位置推送
+func (h *Hub) PushLocation(entityType string, entityID string, loc *dto.LocationResp, viewerIDs []int64) {
+	if len(viewerIDs) == 0 {
+		return
+	}
+	if h.broker != nil {
+		err := h.broker.PublishLocationUpdate(entityType, entityID, loc, viewerIDs)
+		if err == nil {
+			return
+		}
+		// 发布失败时退化为本实例投递
+		logger.Warn("publish location update failed", zap.String("entity_type", entityType), zap.String("entity_id", entityID), zap.Error(err))
+	}
+	h.pushLocationLocal(entityType, entityID, loc, viewerIDs)
+}
+
+func (h *Hub) pushLocationLocal(entityType string, entityID string, loc *dto.LocationResp, viewerIDs []int64) {
+	msg := NewMessage(MessageTypeLocationUpdate, map[string]interface{}{
+		"entity_type": entityType,
+		"entity_id":   entityID,
+		"location":    loc,
+	})
+	h.broadcast <- &broadcastMessage{
+		entityKey: subscriptionKey(entityType, entityID),
+		viewers:   userSet(viewerIDs),
+		data:      marshalMessage(msg),
 	}
-	h.Broadcast(msg)
 }
 
-// SendToUser 发送给特定用户
+// SendToUser 发送给特定用户的所有连接（包括其他实例上的连接）
 func (h *Hub) SendToUser(userID int64, message *Message) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	for client := range h.clients {
-		if client.UserID == userID {
-			client.Send <- marshalMessage(message)
+	if h.broker != nil {
+		err := h.broker.PublishWSMessage(userID, message)
+		if err == nil {
+			return
 		}
+		logger.Warn("publish ws message failed", zap.Int64("user_id", userID), zap.Error(err))
 	}
+	h.sendToUserLocal(userID, marshalMessage(message))
+}
+
+func (h *Hub) sendToUserLocal(userID int64, data []byte) {
+	h.broadcast <- &broadcastMessage{
+		viewers: userSet([]int64{userID}),
+		data:    data,
+	}
+}
+
+func userSet(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
 }
 
 // NewClient 创建客户端
 func NewClient(id string, userID int64, hub *Hub, conn *websocket.Conn) *Client {
 	return &Client{
-		ID:     id,
-		UserID: userID,
-		Hub:    hub,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
+		ID:            id,
+		UserID:        userID,
+		Hub:           hub,
+		Conn:          conn,
+		Send:          make(chan []byte, 256),
+		subscriptions: make(map[string]bool),
+		lastPush:      make(map[string]time.Time),
+		pendingPush:   make(map[string][]byte),
+	}
+}
+
+// Subscribe 订阅实体，超过订阅上限时返回 false
+func (c *Client) Subscribe(entityType, entityID string) bool {
+	key := subscriptionKey(entityType, entityID)
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if c.subscriptions[key] {
+		return true
+	}
+	if len(c.subscriptions) >= MaxSubscriptions {
+		return false
 	}
+	c.subscriptions[key] = true
+	return true
+}
+
+// Unsubscribe 取消订阅实体
+func (c *Client) Unsubscribe(entityType, entityID string) {
+	c.subMu.Lock()
+	delete(c.subscriptions, subscriptionKey(entityType, entityID))
+	c.subMu.Unlock()
+}
+
+// IsSubscribed 是否订阅了实体
+func (c *Client) IsSubscribed(entityType, entityID string) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	return c.subscriptions[subscriptionKey(entityType, entityID)]
+}
+
+// wants 客户端是否接收该实体的推送，只有订阅过的实体才推送
+func (c *Client) wants(key string) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	return c.subscriptions[key]
+}
+
+// SendMessage 向该连接发送消息，连接已断开时丢弃
+func (c *Client) SendMessage(message *Message) {
+	c.Hub.broadcast <- &broadcastMessage{client: c, data: marshalMessage(message)}
 }
 
 // ReadPump 处理读取
@@ -209,6 +449,15 @@ func (c *Client) WritePump() {
 	}
 }
 
+func subscriptionKey(entityType, entityID string) string {
+	return entityType + ":" + entityID
+}
+
+// NewMessage 创建消息
+func NewMessage(msgType string, payload interface{}) *Message {
+	return &Message{Type: msgType, Payload: mustMarshal(payload)}
+}
+
 func marshalMessage(msg *Message) []byte {
 	data, _ := json.Marshal(msg)
 	return data
@@ -0,0 +1,204 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"app/service/dto"
+)
+
+// waitFor 轮询等待条件成立，Hub 的状态变化在 Run 协程中异步发生
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// drain 持续读取发送队列直到被 Hub 关闭，返回读到的消息数
+func drain(c *Client) <-chan int {
+	done := make(chan int, 1)
+	go func() {
+		n := 0
+		for range c.Send {
+			n++
+		}
+		done <- n
+	}()
+	return done
+}
+
+func TestHubConcurrentClients(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	const (
+		users          = 20
+		clientsPerUser = 5
+		rounds         = 50
+	)
+
+	var wg sync.WaitGroup
+	for u := 0; u < users; u++ {
+		for i := 0; i < clientsPerUser; i++ {
+			wg.Add(1)
+			go func(userID int64, i int) {
+				defer wg.Done()
+				c := NewClient(fmt.Sprintf("%d-%d", userID, i), userID, hub, nil)
+				done := drain(c)
+				hub.Register(c)
+				for r := 0; r < rounds; r++ {
+					entityID := fmt.Sprint(r % 3)
+					c.Subscribe(EntityUser, entityID)
+					c.SendMessage(NewMessage(MessageTypePong, nil))
+					c.Unsubscribe(EntityUser, entityID)
+				}
+				hub.Unregister(c)
+				<-done
+			}(int64(u+1), i)
+		}
+	}
+
+	// 注册、注销的同时不断推送
+	stop := make(chan struct{})
+	var pushers sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		pushers.Add(1)
+		go func(p int) {
+			defer pushers.Done()
+			viewers := make([]int64, users)
+			for i := range viewers {
+				viewers[i] = int64(i + 1)
+			}
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				switch n % 3 {
+				case 0:
+					hub.PushLocation(EntityUser, fmt.Sprint(n%3), &dto.LocationResp{ID: int64(n)}, viewers)
+				case 1:
+					hub.SendToUser(int64(n%users+1), NewMessage(MessageTypeAck, nil))
+				default:
+					hub.Broadcast(NewMessage(MessageTypePresenceChanged, nil))
+				}
+				_ = hub.IsOnline(int64(p + 1))
+				_ = hub.ConnectionCount()
+			}
+		}(p)
+	}
+
+	wg.Wait()
+	close(stop)
+	pushers.Wait()
+
+	waitFor(t, 5*time.Second, func() bool { return hub.ConnectionCount() == 0 })
+	for u := 1; u <= users; u++ {
+		if hub.IsOnline(int64(u)) {
+			t.Fatalf("user %d still online after all clients unregistered", u)
+		}
+	}
+}
+
+func TestHubEvictsSlowClient(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	slow := NewClient("slow", 1, hub, nil)
+	fast := NewClient("fast", 2, hub, nil)
+	hub.Register(slow)
+	hub.Register(fast)
+	fastDone := drain(fast)
+
+	// 填满慢连接的发送队列，再连续丢弃到阈值
+	total := cap(slow.Send) + maxDroppedMessages
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < total; i++ {
+			hub.SendToUser(slow.UserID, NewMessage(MessageTypeAck, nil))
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run blocked on a slow client")
+	}
+
+	waitFor(t, 5*time.Second, func() bool { return !hub.IsOnline(slow.UserID) })
+
+	// 慢连接的发送队列已关闭：读完积压的消息后通道关闭
+	received := 0
+	for range slow.Send {
+		received++
+	}
+	if received != cap(slow.Send) {
+		t.Fatalf("slow client received %d messages, want %d", received, cap(slow.Send))
+	}
+
+	// 其他连接不受影响
+	hub.SendToUser(fast.UserID, NewMessage(MessageTypeAck, nil))
+	waitFor(t, 5*time.Second, func() bool { return len(fast.Send) == 0 })
+	hub.Unregister(fast)
+	if n := <-fastDone; n != 1 {
+		t.Fatalf("fast client received %d messages, want 1", n)
+	}
+	if hub.ConnectionCount() != 0 {
+		t.Fatalf("connection count = %d, want 0", hub.ConnectionCount())
+	}
+
+	// 重复注销被驱逐的连接是安全的
+	hub.Unregister(slow)
+}
+
+func TestHubPushLocationOnlyToSubscribers(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	idle := NewClient("idle", 1, hub, nil)
+	subscribed := NewClient("subscribed", 1, hub, nil)
+	other := NewClient("other", 1, hub, nil)
+	subscribed.Subscribe(EntityUser, "7")
+	other.Subscribe(EntityUser, "8")
+	for _, c := range []*Client{idle, subscribed, other} {
+		hub.Register(c)
+	}
+	waitFor(t, 5*time.Second, func() bool { return hub.ConnectionCount() == 3 })
+
+	hub.PushLocation(EntityUser, "7", &dto.LocationResp{ID: 1}, []int64{1})
+	// Hub 按顺序投递，标记消息之前收到的即为位置推送
+	hub.SendToUser(1, NewMessage(MessageTypePong, nil))
+
+	for _, tt := range []struct {
+		client *Client
+		want   int
+	}{
+		{idle, 0},
+		{subscribed, 1},
+		{other, 0},
+	} {
+		pushes := 0
+		for data := range tt.client.Send {
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type == MessageTypePong {
+				break
+			}
+			pushes++
+		}
+		if pushes != tt.want {
+			t.Fatalf("client %s received %d location updates, want %d", tt.client.ID, pushes, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package geo
+
+import "math"
+
+// EarthRadiusMeters 地球平均半径（米）
+const EarthRadiusMeters = 6371008.8
+
+// Distance 计算两点间的球面距离（米），使用 Haversine 公式
+func Distance(lon1, lat1, lon2, lat2 float64) float64 {
+	phi1 := toRadians(lat1)
+	phi2 := toRadians(lat2)
+	dPhi := toRadians(lat2 - lat1)
+	dLambda := toRadians(lon2 - lon1)
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    json.RawMessage `json:"geometry"`
+}
+
+// ParseGeoJSON 解析 Polygon / MultiPolygon 几何对象，也接受包裹它们的 Feature
+func ParseGeoJSON(data []byte) (MultiPolygon, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	var mp MultiPolygon
+	switch obj.Type {
+	case "Feature":
+		if len(obj.Geometry) == 0 {
+			return nil, ErrEmptyGeometry
+		}
+		return ParseGeoJSON(obj.Geometry)
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		poly, err := toPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		mp = MultiPolygon{poly}
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		for _, c := range coords {
+			poly, err := toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			mp = append(mp, poly)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geojson type: %s", obj.Type)
+	}
+	return mp.Normalize()
+}
+
+// GeoJSON 输出 GeoJSON 几何对象，单个多边形输出为 Polygon
+func (mp MultiPolygon) GeoJSON() ([]byte, error) {
+	if len(mp) == 1 {
+		return json.Marshal(map[string]interface{}{
+			"type":        "Polygon",
+			"coordinates": fromPolygon(mp[0]),
+		})
+	}
+	coords := make([][][][]float64, len(mp))
+	for i, poly := range mp {
+		coords[i] = fromPolygon(poly)
+	}
+	return json.Marshal(map[string]interface{}{
+		"type":        "MultiPolygon",
+		"coordinates": coords,
+	})
+}
+
+func toPolygon(coords [][][]float64) (Polygon, error) {
+	poly := make(Polygon, len(coords))
+	for i, ringCoords := range coords {
+		ring := make(Ring, len(ringCoords))
+		for j, c := range ringCoords {
+			if len(c) < 2 {
+				return nil, fmt.Errorf("geojson: position must have at least 2 values")
+			}
+			ring[j] = Point{Lon: c[0], Lat: c[1]}
+		}
+		poly[i] = ring
+	}
+	return poly, nil
+}
+
+func fromPolygon(poly Polygon) [][][]float64 {
+	coords := make([][][]float64, len(poly))
+	for i, ring := range poly {
+		coords[i] = make([][]float64, len(ring))
+		for j, p := range ring {
+			coords[i][j] = []float64{p.Lon, p.Lat}
+		}
+	}
+	return coords
+}
@@ -0,0 +1,83 @@
+package geo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseGeoJSON(t *testing.T) {
+	closedSquare := Ring{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	hole := Ring{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}
+
+	tests := []struct {
+		name string
+		json string
+		want MultiPolygon
+	}{
+		{"polygon", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`, MultiPolygon{{closedSquare}}},
+		{"closes ring", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}`, MultiPolygon{{closedSquare}}},
+		{"altitude ignored", `{"type":"Polygon","coordinates":[[[0,0,5],[10,0,5],[10,10,5],[0,10,5]]]}`, MultiPolygon{{closedSquare}}},
+		{"hole", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]],[[4,4],[6,4],[6,6],[4,6]]]}`, MultiPolygon{{closedSquare, hole}}},
+		{"multipolygon", `{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,10]]],[[[4,4],[6,4],[6,6],[4,6],[4,4]]]]}`, MultiPolygon{{closedSquare}, {hole}}},
+		{"feature", `{"type":"Feature","properties":{"name":"x"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}}`, MultiPolygon{{closedSquare}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGeoJSON([]byte(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+
+			// 输出的 GeoJSON 可被重新解析
+			data, err := got.GeoJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := ParseGeoJSON(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, got) {
+				t.Fatalf("round trip got %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want error
+	}{
+		{"not json", `POLYGON((0 0,1 0,1 1))`, nil},
+		{"truncated", `{"type":"Polygon","coordinates":[[[0,0],[10,0]`, nil},
+		{"point", `{"type":"Point","coordinates":[1,2]}`, nil},
+		{"missing type", `{"coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}`, nil},
+		{"feature collection", `{"type":"FeatureCollection","features":[]}`, nil},
+		{"feature without geometry", `{"type":"Feature","properties":{}}`, ErrEmptyGeometry},
+		{"feature with null geometry", `{"type":"Feature","geometry":null}`, nil},
+		{"polygon with multipolygon coordinates", `{"type":"Polygon","coordinates":[[[[0,0],[10,0],[10,10],[0,10]]]]}`, nil},
+		{"coordinates not numbers", `{"type":"Polygon","coordinates":[[["0","0"],["10","0"],["10","10"]]]}`, nil},
+		{"position with one value", `{"type":"Polygon","coordinates":[[[0],[10,0],[10,10],[0,10]]]}`, nil},
+		{"no rings", `{"type":"Polygon","coordinates":[]}`, ErrEmptyGeometry},
+		{"no polygons", `{"type":"MultiPolygon","coordinates":[]}`, ErrEmptyGeometry},
+		{"too few points", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[0,0]]]}`, ErrRingTooShort},
+		{"out of range", `{"type":"Polygon","coordinates":[[[0,0],[0,95],[10,95],[10,0]]]}`, ErrInvalidCoordinate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp, err := ParseGeoJSON([]byte(tt.json))
+			if err == nil {
+				t.Fatalf("expected error, got %v", mp)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+// Point 经纬度坐标点
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// Ring 闭合线环，首尾点相同
+type Ring []Point
+
+// Polygon 多边形，第一个环为外边界，其余为内洞
+type Polygon []Ring
+
+// MultiPolygon 多多边形
+type MultiPolygon []Polygon
+
+var (
+	ErrInvalidCoordinate = errors.New("coordinate out of range")
+	ErrRingTooShort      = errors.New("ring must have at least 3 distinct points")
+	ErrEmptyGeometry     = errors.New("empty geometry")
+)
+
+// ValidCoordinate 检查经纬度是否在合法范围内
+func ValidCoordinate(lon, lat float64) bool {
+	return lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90 &&
+		!math.IsNaN(lon) && !math.IsNaN(lat)
+}
+
+// Normalize 校验坐标并闭合所有线环
+func (mp MultiPolygon) Normalize() (MultiPolygon, error) {
+	if len(mp) == 0 {
+		return nil, ErrEmptyGeometry
+	}
+	out := make(MultiPolygon, len(mp))
+	for i, poly := range mp {
+		if len(poly) == 0 {
+			return nil, ErrEmptyGeometry
+		}
+		out[i] = make(Polygon, len(poly))
+		for j, ring := range poly {
+			for _, p := range ring {
+				if !ValidCoordinate(p.Lon, p.Lat) {
+					return nil, ErrInvalidCoordinate
+				}
+			}
+			if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+				ring = append(append(Ring{}, ring...), ring[0])
+			}
+			if len(ring) < 4 || ring.distinct() < 3 {
+				return nil, ErrRingTooShort
+			}
+			out[i][j] = ring
+		}
+	}
+	return out, nil
+}
+
+// Contains 判断点是否在多多边形内（射线法，内洞中的点视为在外，边界上的点视为在内）
+func (mp MultiPolygon) Contains(p Point) bool {
+	for _, poly := range mp {
+		if poly.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 判断点是否在多边形内
+func (poly Polygon) Contains(p Point) bool {
+	if len(poly) == 0 || !poly[0].contains(p) {
+		return false
+	}
+	for _, hole := range poly[1:] {
+		if hole.contains(p) && !hole.onEdge(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains 判断点是否在线环内，边上的点视为在内
+func (r Ring) contains(p Point) bool {
+	if r.onEdge(p) {
+		return true
+	}
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// onEdge 判断点是否恰好在线环的某条边上
+func (r Ring) onEdge(p Point) bool {
+	for i := 1; i < len(r); i++ {
+		a, b := r[i-1], r[i]
+		if (b.Lon-a.Lon)*(p.Lat-a.Lat) != (p.Lon-a.Lon)*(b.Lat-a.Lat) {
+			continue
+		}
+		if p.Lon >= math.Min(a.Lon, b.Lon) && p.Lon <= math.Max(a.Lon, b.Lon) &&
+			p.Lat >= math.Min(a.Lat, b.Lat) && p.Lat <= math.Max(a.Lat, b.Lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// distinct 统计闭合线环中不同顶点的数量
+func (r Ring) distinct() int {
+	seen := make(map[Point]struct{}, len(r))
+	for _, p := range r {
+		seen[p] = struct{}{}
+	}
+	return len(seen)
+}
+
+// DistanceToBoundary 计算点到多多边形边界的最短距离（米）
+// 在点附近使用等距圆柱投影近似，适用于城市级范围的围栏
+func (mp MultiPolygon) DistanceToBoundary(p Point) float64 {
+	best := math.Inf(1)
+	for _, poly := range mp {
+		for _, ring := range poly {
+			for i := 1; i < len(ring); i++ {
+				if d := segmentDistance(p, ring[i-1], ring[i]); d < best {
+					best = d
+				}
+			}
+		}
+	}
+	return best
+}
+
+// SignedDistance 点到边界的有向距离（米），在内部为负，外部为正
+func (mp MultiPolygon) SignedDistance(p Point) float64 {
+	d := mp.DistanceToBoundary(p)
+	if mp.Contains(p) {
+		return -d
+	}
+	return d
+}
+
+// Bounds 返回外接矩形的西南角与东北角
+func (mp MultiPolygon) Bounds() (Point, Point) {
+	min := Point{Lon: math.Inf(1), Lat: math.Inf(1)}
+	max := Point{Lon: math.Inf(-1), Lat: math.Inf(-1)}
+	for _, poly := range mp {
+		if len(poly) == 0 {
+			continue
+		}
+		for _, p := range poly[0] {
+			min.Lon = math.Min(min.Lon, p.Lon)
+			min.Lat = math.Min(min.Lat, p.Lat)
+			max.Lon = math.Max(max.Lon, p.Lon)
+			max.Lat = math.Max(max.Lat, p.Lat)
+		}
+	}
+	return min, max
+}
+
+// BoundingCircle 返回覆盖所有外边界顶点的圆（圆心取外接矩形中心）
+func (mp MultiPolygon) BoundingCircle() (Point, float64) {
+	min, max := mp.Bounds()
+	center := Point{Lon: (min.Lon + max.Lon) / 2, Lat: (min.Lat + max.Lat) / 2}
+	radius := 0.0
+	for _, poly := range mp {
+		if len(poly) == 0 {
+			continue
+		}
+		for _, p := range poly[0] {
+			radius = math.Max(radius, Distance(center.Lon, center.Lat, p.Lon, p.Lat))
+		}
+	}
+	return center, radius
+}
+
+// segmentDistance 点到线段的距离（米）
+func segmentDistance(p, a, b Point) float64 {
+	ax, ay := project(p, a)
+	bx, by := project(p, b)
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l2))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// project 以 origin 为原点将坐标投影为平面米坐标
+func project(origin, p Point) (float64, float64) {
+	x := toRadians(p.Lon-origin.Lon) * math.Cos(toRadians(origin.Lat)) * EarthRadiusMeters
+	y := toRadians(p.Lat-origin.Lat) * EarthRadiusMeters
+	return x, y
+}
@@ -0,0 +1,126 @@
+package geo
+
+import (
+	"errors"
+	"testing"
+)
+
+// square 以 (lon, lat) 为西南角、边长为 size 的逆时针正方形线环（未闭合）
+func square(lon, lat, size float64) Ring {
+	return Ring{{lon, lat}, {lon + size, lat}, {lon + size, lat + size}, {lon, lat + size}}
+}
+
+func mustNormalize(t *testing.T, mp MultiPolygon) MultiPolygon {
+	t.Helper()
+	out, err := mp.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRingContains(t *testing.T) {
+	sq := mustNormalize(t, MultiPolygon{{square(0, 0, 10)}})[0][0]
+	triangle := mustNormalize(t, MultiPolygon{{Ring{{0, 0}, {10, 0}, {0, 10}}}})[0][0]
+	// 凹多边形：U 形
+	concave := mustNormalize(t, MultiPolygon{{Ring{{0, 0}, {10, 0}, {10, 10}, {7, 10}, {7, 3}, {3, 3}, {3, 10}, {0, 10}}}})[0][0]
+
+	tests := []struct {
+		name string
+		ring Ring
+		p    Point
+		want bool
+	}{
+		{"inside", sq, Point{5, 5}, true},
+		{"outside east", sq, Point{11, 5}, false},
+		{"outside north east", sq, Point{11, 11}, false},
+		{"outside in line with edge", sq, Point{-1, 0}, false},
+		{"on west edge", sq, Point{0, 5}, true},
+		{"on east edge", sq, Point{10, 5}, true},
+		{"on south edge", sq, Point{5, 0}, true},
+		{"on north edge", sq, Point{5, 10}, true},
+		{"on vertex", sq, Point{10, 10}, true},
+		{"triangle inside", triangle, Point{2, 2}, true},
+		{"triangle on hypotenuse", triangle, Point{5, 5}, true},
+		{"triangle outside", triangle, Point{6, 6}, false},
+		{"concave arm", concave, Point{1, 8}, true},
+		{"concave notch", concave, Point{5, 8}, false},
+		{"concave notch edge", concave, Point{5, 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ring.contains(tt.p); got != tt.want {
+				t.Fatalf("contains(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiPolygonContains(t *testing.T) {
+	mp := mustNormalize(t, MultiPolygon{
+		{square(0, 0, 10), square(4, 4, 2)},
+		{square(20, 20, 5)},
+	})
+
+	tests := []struct {
+		name string
+		p    Point
+		want bool
+	}{
+		{"first polygon", Point{1, 1}, true},
+		{"in hole", Point{5, 5}, false},
+		{"on hole edge", Point{4, 5}, true},
+		{"on hole vertex", Point{6, 6}, true},
+		{"second polygon", Point{22, 22}, true},
+		{"on second polygon edge", Point{25, 22}, true},
+		{"between polygons", Point{15, 15}, false},
+		{"outside all", Point{-5, -5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mp.Contains(tt.p); got != tt.want {
+				t.Fatalf("Contains(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+			if d := mp.SignedDistance(tt.p); (d <= 0) != tt.want {
+				t.Fatalf("SignedDistance(%v) = %v, want inside=%v", tt.p, d, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	open := MultiPolygon{{square(0, 0, 1)}}
+	closed := mustNormalize(t, open)
+	ring := closed[0][0]
+	if len(ring) != 5 || ring[0] != ring[4] {
+		t.Fatalf("ring not closed: %v", ring)
+	}
+	if len(open[0][0]) != 4 {
+		t.Fatal("normalize modified the input ring")
+	}
+	if again := mustNormalize(t, closed); len(again[0][0]) != 5 {
+		t.Fatalf("closed ring closed twice: %v", again[0][0])
+	}
+
+	tests := []struct {
+		name string
+		mp   MultiPolygon
+		want error
+	}{
+		{"empty", MultiPolygon{}, ErrEmptyGeometry},
+		{"polygon without rings", MultiPolygon{{}}, ErrEmptyGeometry},
+		{"two points", MultiPolygon{{Ring{{0, 0}, {1, 1}}}}, ErrRingTooShort},
+		{"closed two points", MultiPolygon{{Ring{{0, 0}, {1, 1}, {0, 0}}}}, ErrRingTooShort},
+		{"repeated vertex", MultiPolygon{{Ring{{0, 0}, {1, 1}, {1, 1}, {0, 0}}}}, ErrRingTooShort},
+		{"short hole", MultiPolygon{{square(0, 0, 10), Ring{{1, 1}, {2, 2}}}}, ErrRingTooShort},
+		{"longitude out of range", MultiPolygon{{square(179, 0, 2)}}, ErrInvalidCoordinate},
+		{"latitude out of range", MultiPolygon{{square(0, 89.5, 1)}}, ErrInvalidCoordinate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.mp.Normalize(); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
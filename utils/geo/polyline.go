@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// DefaultPolylinePrecision Google 编码折线的默认精度（小数位数）
+const DefaultPolylinePrecision = 5
+
+var ErrInvalidPolyline = errors.New("invalid encoded polyline")
+
+// EncodePolyline 按 Google Encoded Polyline 算法编码折线，precision 为坐标保留的小数位数
+func EncodePolyline(points []Point, precision int) string {
+	factor := math.Pow10(precision)
+	var b strings.Builder
+	var prevLat, prevLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * factor))
+		lon := int64(math.Round(p.Lon * factor))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return b.String()
+}
+
+// DecodePolyline 解码 Google Encoded Polyline，precision 需与编码时一致
+func DecodePolyline(s string, precision int) ([]Point, error) {
+	factor := math.Pow10(precision)
+	var (
+		points   []Point
+		lat, lon int64
+	)
+	for i := 0; i < len(s); {
+		dlat, n, err := decodePolylineValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		dlon, n, err := decodePolylineValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+
+		lat += dlat
+		lon += dlon
+		points = append(points, Point{Lon: float64(lon) / factor, Lat: float64(lat) / factor})
+	}
+	return points, nil
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|(u&0x1f)) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
+
+// decodePolylineValue 解码一个数值，返回数值和消耗的字节数
+func decodePolylineValue(s string) (int64, int, error) {
+	var (
+		result uint64
+		shift  uint
+	)
+	for i := 0; i < len(s); i++ {
+		c := int(s[i]) - 63
+		if c < 0 || c > 0x3f || shift > 60 {
+			return 0, 0, ErrInvalidPolyline
+		}
+		result |= uint64(c&0x1f) << shift
+		shift += 5
+		if c < 0x20 {
+			v := int64(result >> 1)
+			if result&1 != 0 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrInvalidPolyline
+}
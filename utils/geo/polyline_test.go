@@ -0,0 +1,165 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// googleExample Google Encoded Polyline 文档中的参考示例
+var googleExample = []Point{
+	{Lat: 38.5, Lon: -120.2},
+	{Lat: 40.7, Lon: -120.95},
+	{Lat: 43.252, Lon: -126.453},
+}
+
+const googleExampleEncoded = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+
+func pointsClose(a, b []Point, eps float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i].Lat-b[i].Lat) > eps || math.Abs(a[i].Lon-b[i].Lon) > eps {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEncodePolyline(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Point
+		want   string
+	}{
+		{"google example", googleExample, googleExampleEncoded},
+		{"single point", googleExample[:1], "_p~iF~ps|U"},
+		{"empty path", nil, ""},
+		{"origin", []Point{{}}, "??"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodePolyline(tt.points, DefaultPolylinePrecision); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	got, err := DecodePolyline(googleExampleEncoded, DefaultPolylinePrecision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pointsClose(got, googleExample, 1e-9) {
+		t.Fatalf("got %v, want %v", got, googleExample)
+	}
+
+	got, err = DecodePolyline("", DefaultPolylinePrecision)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("empty: got %v, %v", got, err)
+	}
+}
+
+func TestPolylineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []Point
+		precision int
+	}{
+		{"negative coordinates", []Point{{Lon: -58.3816, Lat: -34.6037}, {Lon: -43.1729, Lat: -22.9068}, {Lon: 151.2093, Lat: -33.8688}}, 5},
+		{"crossing zero", []Point{{Lon: -0.00001, Lat: 0.00001}, {Lon: 0.00001, Lat: -0.00001}}, 5},
+		{"extremes", []Point{{Lon: -180, Lat: -90}, {Lon: 180, Lat: 90}}, 5},
+		{"single point", []Point{{Lon: 116.39747, Lat: 39.90872}}, 5},
+		{"precision 6", []Point{{Lon: 116.397472, Lat: 39.908722}, {Lon: 116.397473, Lat: 39.908721}}, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := EncodePolyline(tt.points, tt.precision)
+			got, err := DecodePolyline(encoded, tt.precision)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pointsClose(got, tt.points, 0.5/math.Pow10(tt.precision)) {
+				t.Fatalf("got %v, want %v", got, tt.points)
+			}
+		})
+	}
+}
+
+func TestDecodePolylineInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"truncated value", googleExampleEncoded[:3]},
+		{"missing longitude", "_p~iF"},
+		{"character below range", "_p~iF~ps|U "},
+		{"character above range", "_p~iF\x7f"},
+		{"overlong value", "~~~~~~~~~~~~~~?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePolyline(tt.s, DefaultPolylinePrecision); !errors.Is(err, ErrInvalidPolyline) {
+				t.Fatalf("err = %v, want ErrInvalidPolyline", err)
+			}
+		})
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	// 沿纬线约每 100 米一个点的直线，中间一个点偏离约 55 米
+	line := make([]Point, 11)
+	for i := range line {
+		line[i] = Point{Lon: 116.0 + float64(i)*0.0012, Lat: 39.9}
+	}
+	spiked := append([]Point(nil), line...)
+	spiked[5].Lat += 0.0005
+
+	tests := []struct {
+		name      string
+		points    []Point
+		tolerance float64
+		want      []int
+	}{
+		{"empty", nil, 10, []int{}},
+		{"single point", line[:1], 10, []int{0}},
+		{"two points", line[:2], 10, []int{0, 1}},
+		{"no tolerance keeps all", line[:4], 0, []int{0, 1, 2, 3}},
+		{"straight line keeps endpoints", line, 10, []int{0, 10}},
+		{"spike above tolerance", spiked, 50, []int{0, 5, 10}},
+		{"spike with tight tolerance", spiked, 10, []int{0, 4, 5, 6, 10}},
+		{"spike below tolerance", spiked, 100, []int{0, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Simplify(tt.points, tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyPreservesEndpoints(t *testing.T) {
+	// 闭合环路：首尾重合时首尾点也必须保留
+	loop := []Point{
+		{Lon: 116.0, Lat: 39.9},
+		{Lon: 116.01, Lat: 39.9},
+		{Lon: 116.01, Lat: 39.91},
+		{Lon: 116.0, Lat: 39.91},
+		{Lon: 116.0, Lat: 39.9},
+	}
+	for _, tolerance := range []float64{1, 100, 1e6} {
+		kept := Simplify(loop, tolerance)
+		if len(kept) < 2 || kept[0] != 0 || kept[len(kept)-1] != len(loop)-1 {
+			t.Fatalf("tolerance %v: endpoints not preserved: %v", tolerance, kept)
+		}
+		for i := 1; i < len(kept); i++ {
+			if kept[i] <= kept[i-1] {
+				t.Fatalf("tolerance %v: indexes not ascending: %v", tolerance, kept)
+			}
+		}
+	}
+}
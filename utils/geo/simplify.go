@@ -0,0 +1,53 @@
+package geo
+
+// Simplify 使用 Douglas-Peucker 算法简化折线，返回保留点的下标（升序）
+// tolerance 为简化后允许的最大偏差（米），首尾点始终保留；tolerance <= 0 时不简化
+func Simplify(points []Point, tolerance float64) []int {
+	n := len(points)
+	if n <= 2 || tolerance <= 0 {
+		kept := make([]int, n)
+		for i := range kept {
+			kept[i] = i
+		}
+		return kept
+	}
+
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+
+	// 使用显式栈代替递归，避免长轨迹递归过深
+	type span struct{ first, last int }
+	stack := []span{{0, n - 1}}
+	for len(stack) > 0 {
+		sp := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		maxDist, index := 0.0, -1
+		for i := sp.first + 1; i < sp.last; i++ {
+			if d := segmentDistance(points[i], points[sp.first], points[sp.last]); d > maxDist {
+				maxDist, index = d, i
+			}
+		}
+		if index >= 0 && maxDist > tolerance {
+			keep[index] = true
+			stack = append(stack, span{sp.first, index}, span{index, sp.last})
+		}
+	}
+
+	kept := make([]int, 0, n)
+	for i, k := range keep {
+		if k {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+// PathLength 计算折线总长度（米）
+func PathLength(points []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += Distance(points[i-1].Lon, points[i-1].Lat, points[i].Lon, points[i].Lat)
+	}
+	return total
+}
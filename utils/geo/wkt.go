@@ -0,0 +1,197 @@
+package geo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseWKT 解析 POLYGON / MULTIPOLYGON 格式的 WKT，允许带 "SRID=4326;" 前缀，其他坐标系拒绝
+func ParseWKT(wkt string) (MultiPolygon, error) {
+	s := strings.TrimSpace(wkt)
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		idx := strings.Index(s, ";")
+		if idx < 0 {
+			return nil, fmt.Errorf("wkt: missing ';' after srid")
+		}
+		if srid := strings.TrimSpace(s[len("SRID="):idx]); srid != "4326" {
+			return nil, fmt.Errorf("wkt: unsupported srid %q", srid)
+		}
+		s = strings.TrimSpace(s[idx+1:])
+	}
+
+	upper := strings.ToUpper(s)
+	var (
+		mp  MultiPolygon
+		err error
+	)
+	switch {
+	case strings.HasPrefix(upper, "MULTIPOLYGON"):
+		p := &wktParser{s: s, pos: len("MULTIPOLYGON")}
+		mp, err = p.multiPolygon()
+		if err == nil {
+			err = p.end()
+		}
+	case strings.HasPrefix(upper, "POLYGON"):
+		p := &wktParser{s: s, pos: len("POLYGON")}
+		var poly Polygon
+		poly, err = p.polygon()
+		if err == nil {
+			err = p.end()
+		}
+		mp = MultiPolygon{poly}
+	default:
+		return nil, fmt.Errorf("unsupported wkt geometry: %.20s", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mp.Normalize()
+}
+
+// WKT 输出 WKT 格式
+func (mp MultiPolygon) WKT() string {
+	var b strings.Builder
+	if len(mp) == 1 {
+		b.WriteString("POLYGON")
+		writeWKTPolygon(&b, mp[0])
+		return b.String()
+	}
+	b.WriteString("MULTIPOLYGON(")
+	for i, poly := range mp {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeWKTPolygon(&b, poly)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func writeWKTPolygon(b *strings.Builder, poly Polygon) {
+	b.WriteByte('(')
+	for i, ring := range poly {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for j, p := range ring {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.FormatFloat(p.Lon, 'f', -1, 64))
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(p.Lat, 'f', -1, 64))
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(')')
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *wktParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != c {
+		return fmt.Errorf("wkt: expected '%c' at offset %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// next 判断下一个非空白字符是否为 c，是则消费
+func (p *wktParser) next(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *wktParser) end() error {
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return fmt.Errorf("wkt: unexpected trailing data at offset %d", p.pos)
+	}
+	return nil
+}
+
+func (p *wktParser) multiPolygon() (MultiPolygon, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var mp MultiPolygon
+	for {
+		poly, err := p.polygon()
+		if err != nil {
+			return nil, err
+		}
+		mp = append(mp, poly)
+		if !p.next(',') {
+			break
+		}
+	}
+	return mp, p.expect(')')
+}
+
+func (p *wktParser) polygon() (Polygon, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var poly Polygon
+	for {
+		ring, err := p.ring()
+		if err != nil {
+			return nil, err
+		}
+		poly = append(poly, ring)
+		if !p.next(',') {
+			break
+		}
+	}
+	return poly, p.expect(')')
+}
+
+func (p *wktParser) ring() (Ring, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var ring Ring
+	for {
+		lon, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		lat, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		ring = append(ring, Point{Lon: lon, Lat: lat})
+		if !p.next(',') {
+			break
+		}
+	}
+	return ring, p.expect(')')
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("wkt: expected number at offset %d", start)
+	}
+	return strconv.ParseFloat(p.s[start:p.pos], 64)
+}
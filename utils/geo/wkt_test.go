@@ -0,0 +1,86 @@
+package geo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseWKT(t *testing.T) {
+	closedSquare := Ring{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	hole := Ring{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}
+
+	tests := []struct {
+		name string
+		wkt  string
+		want MultiPolygon
+	}{
+		{"polygon", "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", MultiPolygon{{closedSquare}}},
+		{"closes ring", "POLYGON((0 0, 10 0, 10 10, 0 10))", MultiPolygon{{closedSquare}}},
+		{"lower case and spaces", "  polygon ( ( 0 0 ,10 0,10 10,\n0 10 ) ) ", MultiPolygon{{closedSquare}}},
+		{"hole", "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0), (4 4, 6 4, 6 6, 4 6))", MultiPolygon{{closedSquare, hole}}},
+		{"multipolygon", "MULTIPOLYGON(((0 0, 10 0, 10 10, 0 10)), ((4 4, 6 4, 6 6, 4 6, 4 4)))", MultiPolygon{{closedSquare}, {hole}}},
+		{"srid 4326", "SRID=4326;POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", MultiPolygon{{closedSquare}}},
+		{"exponent", "POLYGON((0 0, 1e1 0, 10 1E1, 0 10))", MultiPolygon{{closedSquare}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWKT(tt.wkt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWKTInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+		want error
+	}{
+		{"trailing data", "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0)) POINT(1 1)", nil},
+		{"trailing paren", "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0)))", nil},
+		{"too few points", "POLYGON((0 0, 10 0, 0 0))", ErrRingTooShort},
+		{"repeated points", "POLYGON((0 0, 10 0, 10 0, 0 0))", ErrRingTooShort},
+		{"other srid", "SRID=3857;POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", nil},
+		{"invalid srid", "SRID=abc;POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", nil},
+		{"srid without separator", "SRID=4326 POLYGON((0 0, 10 0, 10 10, 0 10, 0 0))", nil},
+		{"empty", "", nil},
+		{"point", "POINT(1 2)", nil},
+		{"polygon empty", "POLYGON EMPTY", nil},
+		{"missing latitude", "POLYGON((0 0, 10, 10 10, 0 10))", nil},
+		{"unclosed paren", "POLYGON((0 0, 10 0, 10 10, 0 10, 0 0)", nil},
+		{"bad number", "POLYGON((0 0, 1-0 0, 10 10, 0 10))", nil},
+		{"out of range", "POLYGON((0 0, 190 0, 190 10, 0 10))", ErrInvalidCoordinate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp, err := ParseWKT(tt.wkt)
+			if err == nil {
+				t.Fatalf("expected error, got %v", mp)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWKTRoundTrip(t *testing.T) {
+	for _, wkt := range []string{
+		"POLYGON((116.39 39.9,116.4 39.9,116.4 39.91,116.39 39.91,116.39 39.9))",
+		"MULTIPOLYGON(((0 0,10 0,10 10,0 10,0 0),(4 4,6 4,6 6,4 6,4 4)),((20 20,25 20,25 25,20 20)))",
+	} {
+		mp, err := ParseWKT(wkt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mp.WKT(); got != wkt {
+			t.Fatalf("WKT() = %s, want %s", got, wkt)
+		}
+	}
+}